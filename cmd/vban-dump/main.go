@@ -0,0 +1,225 @@
+// Command vban-dump prints the header of every VBAN packet received on a UDP port, one
+// line per packet, and reports frame counter gaps per stream. With -stats, it prints a
+// table of per-stream statistics at the given interval instead, e.g. to monitor the health
+// of a talkback stream.
+//
+// Usage:
+//
+//	vban-dump [-listen :6980|group:port] [-stream name] [-proto audio|serial|text|service] [-stats 5s]
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hrko/go-vban/vban"
+)
+
+// streamKey identifies a stream by sub-protocol, name and sender.
+type streamKey struct {
+	proto  vban.SubProtocol
+	name   string
+	source string
+}
+
+// streamState tracks the frame counter of one stream.
+type streamState struct {
+	next      uint32 // Expected NuFrame of the next packet
+	packets   uint64
+	lost      uint64 // Frames skipped by the counter
+	reordered uint64 // Packets with a counter below the expected value
+}
+
+func main() {
+	// --- Argument Parsing ---
+	listenAddrStr := flag.String("listen", fmt.Sprintf(":%d", vban.DefaultPort), "Local address to listen on, or a multicast group to join")
+	streamFilter := flag.String("stream", "", "Only show packets of this stream name")
+	protoFilter := flag.String("proto", "", "Only show this sub-protocol (audio, serial, text or service)")
+	statsInterval := flag.Duration("stats", 0, "Print per-stream statistics at this interval instead of packets")
+	flag.Parse()
+
+	var wantProto vban.SubProtocol
+	haveProto := *protoFilter != ""
+	if haveProto {
+		switch strings.ToLower(*protoFilter) {
+		case "audio":
+			wantProto = vban.ProtocolAudio
+		case "serial":
+			wantProto = vban.ProtocolSerial
+		case "text":
+			wantProto = vban.ProtocolText
+		case "service":
+			wantProto = vban.ProtocolService
+		default:
+			log.Fatalf("Unknown sub-protocol '%s'", *protoFilter)
+		}
+	}
+
+	// --- VBAN Connection Setup ---
+	listenAddr, err := net.ResolveUDPAddr("udp", *listenAddrStr)
+	if err != nil {
+		log.Fatalf("Failed to resolve listen address '%s': %v", *listenAddrStr, err)
+	}
+	var conn *vban.Conn
+	if listenAddr.IP.IsMulticast() {
+		conn, err = vban.ListenMulticast(nil, listenAddr)
+	} else {
+		conn, err = vban.Listen(listenAddr)
+	}
+	if err != nil {
+		log.Fatalf("Failed to listen on '%s': %v", listenAddr, err)
+	}
+	log.Printf("Listening for VBAN packets on %s", conn.LocalAddr())
+
+	var collector *vban.StatsCollector
+	if *statsInterval > 0 {
+		collector = vban.NewStatsCollector()
+		conn.SetObserver(collector)
+		go func() {
+			for range time.Tick(*statsInterval) {
+				printStats(collector.Snapshot(), *streamFilter, haveProto, wantProto)
+			}
+		}()
+	}
+
+	// Close the connection on Ctrl-C to end the receive loop.
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		<-sig
+		conn.Close()
+	}()
+
+	// --- Receive Loop ---
+	streams := make(map[streamKey]*streamState)
+	for {
+		packet, addr, err := conn.Receive()
+		if errors.Is(err, vban.ErrClosed) {
+			break
+		}
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		h := &packet.Header
+		proto := h.SubProtocol() & vban.ProtocolMask
+		name := h.GetStreamName()
+		if (haveProto && proto != wantProto) || (*streamFilter != "" && name != *streamFilter) {
+			continue
+		}
+
+		// Track the frame counter of each stream. Service packets are not numbered.
+		key := streamKey{proto: proto, name: name, source: addr.String()}
+		st, ok := streams[key]
+		if !ok {
+			st = &streamState{next: h.NuFrame}
+			streams[key] = st
+		}
+		st.packets++
+		note := ""
+		if d := int32(h.NuFrame - st.next); !proto.IsService() {
+			switch {
+			case d > 0:
+				st.lost += uint64(d)
+				note = fmt.Sprintf("  GAP: %d frame(s) missing", d)
+			case d < 0:
+				st.reordered++
+				note = fmt.Sprintf("  OUT OF ORDER: expected #%d", st.next)
+			}
+			if d >= 0 || d < -1024 {
+				st.next = h.NuFrame + 1 // Advance, or resynchronize after a sender restart
+			}
+		}
+
+		if collector != nil {
+			continue // Packets are only counted
+		}
+		fmt.Printf("%s %-21s %-7s %-16q %s #%d%s\n",
+			time.Now().Format("15:04:05.000"), addr, proto, name, describe(h, len(packet.Data)), h.NuFrame, note)
+	}
+
+	// --- Summary ---
+	keys := make([]streamKey, 0, len(streams))
+	for key := range streams {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].name != keys[j].name {
+			return keys[i].name < keys[j].name
+		}
+		return keys[i].source < keys[j].source
+	})
+	fmt.Println()
+	for _, key := range keys {
+		st := streams[key]
+		fmt.Printf("%-7s %-16q from %-21s packets: %d, lost frames: %d, out of order: %d\n",
+			key.proto, key.name, key.source, st.packets, st.lost, st.reordered)
+	}
+}
+
+// printStats prints one line of statistics per stream matching the filters.
+func printStats(stats []vban.StreamStats, streamFilter string, haveProto bool, wantProto vban.SubProtocol) {
+	fmt.Printf("\n%s\n", time.Now().Format("15:04:05.000"))
+	for _, s := range stats {
+		if (haveProto && s.Protocol != wantProto) || (streamFilter != "" && s.StreamName != streamFilter) {
+			continue
+		}
+		fmt.Printf("%-7s %-16q from %-21s %7.1f pkt/s %8.1f kbit/s  lost: %d (%.2f%%)  dup: %d  ooo: %d  jitter: %v  format changes: %d  last seen: %v ago\n",
+			s.Protocol, s.StreamName, s.Source, s.PacketRate, s.ByteRate*8/1000, s.Lost, 100*s.LossRatio(),
+			s.Duplicates, s.OutOfOrder, s.Jitter.Round(10*time.Microsecond), s.FormatChanges, time.Since(s.LastSeen).Round(time.Millisecond))
+	}
+}
+
+// describe formats the sub-protocol specific fields of a header.
+func describe(h *vban.Header, size int) string {
+	proto := h.SubProtocol()
+	switch {
+	case proto.IsAudio():
+		codec := "PCM"
+		switch h.CodecType() {
+		case vban.CodecPCM:
+		case vban.CodecVBCA:
+			codec = "VBCA"
+		case vban.CodecVBCV:
+			codec = "VBCV"
+		default:
+			codec = fmt.Sprintf("codec %#02x", uint8(h.CodecType()))
+		}
+		return fmt.Sprintf("%6d Hz %3d ch %-7s %-4s %3d smp %4d B",
+			h.SRIndex().GetRate(proto), int(h.FormatNbc)+1, h.DataType(), codec, int(h.FormatNbs)+1, size)
+	case proto.IsSerial():
+		kind := "GENERIC"
+		switch h.CodecType() {
+		case vban.SerialGeneric:
+		case vban.SerialMIDI:
+			kind = "MIDI"
+		default:
+			kind = fmt.Sprintf("type %#02x", uint8(h.CodecType()))
+		}
+		return fmt.Sprintf("%6d bps ch %-3d %-7s %-7s %4d B", h.SRIndex().GetRate(proto), h.ChannelIdent(), h.DataType(), kind, size)
+	case proto.IsText():
+		format := "ASCII"
+		switch h.CodecType() {
+		case vban.TextASCII:
+		case vban.TextUTF8:
+			format = "UTF8"
+		case vban.TextWCHAR:
+			format = "WCHAR"
+		default:
+			format = fmt.Sprintf("format %#02x", uint8(h.CodecType()))
+		}
+		return fmt.Sprintf("%6d bps %-7s %4d B", h.SRIndex().GetRate(proto), format, size)
+	case proto.IsService():
+		return fmt.Sprintf("type %d function %#02x %4d B", h.ServiceType(), uint8(h.ServiceFunction()), size)
+	default:
+		return fmt.Sprintf("%4d B", size)
+	}
+}
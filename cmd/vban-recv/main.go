@@ -0,0 +1,217 @@
+// Command vban-recv receives one VBAN audio stream and writes it to stdout or a file,
+// either as raw PCM or as a WAV file.
+//
+// Raw output uses the stream's native sample format (or float32 with -float), so it can be
+// piped into other tools, e.g.:
+//
+//	vban-recv -stream Stream1 | ffplay -f s16le -ar 48000 -ac 2 -
+//
+// WAV output is written in the stream's native format, starting a new file (out-2.wav,
+// out-3.wav, ...) if the stream format changes or the stream is interrupted.
+//
+// Usage:
+//
+//	vban-recv -stream name [-listen :6980|group:port] [-source ip] [-o file|-] [-wav] [-float] [-latency 40ms]
+//	          [-rcvbuf bytes] [-reuseport]
+package main
+
+import (
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hrko/go-vban/vban"
+)
+
+func main() {
+	// --- Argument Parsing ---
+	listenAddrStr := flag.String("listen", fmt.Sprintf(":%d", vban.DefaultPort), "Local address to listen on, or a multicast group to join")
+	streamName := flag.String("stream", "", "VBAN stream name (required)")
+	sourceStr := flag.String("source", "", "Only accept packets from this IP address")
+	output := flag.String("o", "-", "Output file, or - for stdout")
+	wav := flag.Bool("wav", false, "Write a WAV file (default if the output name ends in .wav)")
+	float := flag.Bool("float", false, "Write raw PCM as 32-bit float instead of the stream's format")
+	latency := flag.Duration("latency", vban.DefaultReceiverLatency, "Jitter buffer latency for raw output")
+	readBuffer := flag.Int("rcvbuf", 0, "Socket receive buffer size in bytes (0 for the system default)")
+	reusePort := flag.Bool("reuseport", false, "Share the port with other receivers (SO_REUSEPORT)")
+	flag.Parse()
+
+	if *streamName == "" {
+		fmt.Fprintln(os.Stderr, "Error: --stream flag is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+	// Logs go to stderr so that stdout carries only audio.
+	log.SetOutput(os.Stderr)
+	if strings.EqualFold(filepath.Ext(*output), ".wav") {
+		*wav = true
+	}
+	if *wav && *output == "-" {
+		log.Fatalf("WAV output requires a file name (-o)")
+	}
+
+	var source *net.UDPAddr
+	if *sourceStr != "" {
+		ip := net.ParseIP(*sourceStr)
+		if ip == nil {
+			log.Fatalf("Invalid source IP address '%s'", *sourceStr)
+		}
+		source = &net.UDPAddr{IP: ip}
+	}
+
+	// --- VBAN Connection Setup ---
+	listenAddr, err := net.ResolveUDPAddr("udp", *listenAddrStr)
+	if err != nil {
+		log.Fatalf("Failed to resolve listen address '%s': %v", *listenAddrStr, err)
+	}
+	var opts []vban.Option
+	if *readBuffer > 0 {
+		opts = append(opts, vban.WithReadBuffer(*readBuffer))
+	}
+	if *reusePort {
+		opts = append(opts, vban.WithReusePort())
+	}
+	var conn *vban.Conn
+	if listenAddr.IP.IsMulticast() {
+		conn, err = vban.ListenMulticast(nil, listenAddr, opts...)
+	} else {
+		conn, err = vban.Listen(listenAddr, opts...)
+	}
+	if err != nil {
+		log.Fatalf("Failed to listen on '%s': %v", listenAddr, err)
+	}
+	log.Printf("Receiving VBAN stream '%s' on %s", *streamName, conn.LocalAddr())
+
+	// Close the connection on Ctrl-C; the receiver then finishes its output.
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		<-sig
+		conn.Close()
+	}()
+
+	if *wav {
+		recordWAV(conn, *streamName, source, *output)
+	} else {
+		writeRaw(conn, *streamName, source, *output, *float, *latency)
+	}
+}
+
+// recordWAV writes the stream to WAV files until the connection is closed.
+func recordWAV(conn *vban.Conn, streamName string, source *net.UDPAddr, output string) {
+	ext := filepath.Ext(output)
+	base := strings.TrimSuffix(output, ext)
+	files := 0
+	rec, err := vban.NewWAVRecorder(conn, vban.WAVRecorderConfig{
+		StreamName: streamName,
+		Source:     source,
+		Path: func(string, time.Time) string {
+			files++
+			if files == 1 {
+				return output
+			}
+			return fmt.Sprintf("%s-%d%s", base, files, ext)
+		},
+		OnClose: func(path string, err error) {
+			if err != nil {
+				log.Printf("Error writing '%s': %v", path, err)
+				return
+			}
+			log.Printf("Finished '%s'", path)
+		},
+	})
+	if err != nil {
+		log.Fatalf("Failed to create recorder: %v", err)
+	}
+	if err := rec.Run(); err != nil {
+		log.Fatalf("Error receiving stream: %v", err)
+	}
+	stats := rec.Stats()
+	log.Printf("Packets: %d, frames: %d, silence inserted: %d frames, dropped: %d",
+		stats.Packets, stats.Frames, stats.Silence, stats.Dropped)
+}
+
+// writeRaw copies the reassembled stream to the output until the connection is closed.
+func writeRaw(conn *vban.Conn, streamName string, source *net.UDPAddr, output string, float bool, latency time.Duration) {
+	out := os.Stdout
+	if output != "-" {
+		f, err := os.Create(output)
+		if err != nil {
+			log.Fatalf("Failed to create '%s': %v", output, err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	receiver, err := vban.NewAudioReceiver(nil, vban.AudioReceiverConfig{
+		StreamName: streamName,
+		Source:     source,
+		Latency:    latency,
+		OnGap: func(first uint32, count int) {
+			log.Printf("Warning: %d frame(s) lost at #%d", count, first)
+		},
+	})
+	if err != nil {
+		log.Fatalf("Failed to create receiver: %v", err)
+	}
+
+	// Report the format with the first packet of the stream, since raw PCM does not carry it.
+	reported := false
+	mux := vban.NewMux()
+	mux.HandleFunc(vban.ProtocolAudio, streamName, func(p *vban.Packet, addr *net.UDPAddr) {
+		receiver.HandlePacket(p, addr)
+		if reported {
+			return
+		}
+		if format, ok := receiver.Format(); ok {
+			reported = true
+			dataType := format.DataType.String()
+			if float {
+				dataType = "FLOAT32"
+			}
+			log.Printf("Stream format: %d Hz, %d channel(s), %s", format.SampleRate(), format.Channels, dataType)
+		}
+	})
+	go func() {
+		defer receiver.Close()
+		if err := mux.Serve(conn); err != nil {
+			log.Printf("Error receiving stream: %v", err)
+		}
+	}()
+
+	if !float {
+		if _, err := io.Copy(out, receiver); err != nil {
+			log.Fatalf("Failed to write output: %v", err)
+		}
+	} else {
+		samples := make([]float32, 4096)
+		buf := make([]byte, 4*len(samples))
+		for {
+			n, err := receiver.ReadFloat32(samples)
+			for i, v := range samples[:n] {
+				binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+			}
+			if _, werr := out.Write(buf[:4*n]); werr != nil {
+				log.Fatalf("Failed to write output: %v", werr)
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				log.Fatalf("Error reading stream: %v", err)
+			}
+		}
+	}
+	stats := receiver.Stats()
+	log.Printf("Packets: %d, lost frames: %d, late: %d, duplicates: %d",
+		stats.Received, stats.Lost, stats.Late, stats.Duplicates)
+}
@@ -0,0 +1,250 @@
+// Command vban-relay receives VBAN packets and forwards selected streams to many
+// destinations, optionally renaming them and renumbering their frame counters.
+//
+// A single route is configured with flags, e.g. to fan a program feed out to several
+// endpoints:
+//
+//	vban-relay -stream Program -rename PGM -to 10.1.0.10:6980,10.2.0.10:6980 -to-file endpoints.txt
+//
+// Several routes can be loaded from a JSON file with -config:
+//
+//	{"routes": [
+//	  {"stream": "Program", "source": "192.168.1.5", "rename": "PGM", "renumber": true,
+//	   "protocols": ["audio"], "to": ["10.1.0.10:6980", "10.2.0.10:6980"]}
+//	]}
+//
+// Usage:
+//
+//	vban-relay [-listen :6980|group:port] [-bind addr] [-stream name] [-source ip] [-rename name] [-renumber]
+//	           [-to addr,...] [-to-file file] [-config file] [-broadcast]
+//	           [-rcvbuf bytes] [-dscp 46] [-stats 10s]
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/hrko/go-vban/vban"
+)
+
+// routeConfig is the JSON form of a vban.RelayRoute.
+type routeConfig struct {
+	Stream    string   `json:"stream"`
+	Source    string   `json:"source"`
+	Rename    string   `json:"rename"`
+	Renumber  bool     `json:"renumber"`
+	Protocols []string `json:"protocols"`
+	To        []string `json:"to"`
+}
+
+// listFlag collects the values of a repeatable, comma-separated flag.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(v string) error {
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			*l = append(*l, s)
+		}
+	}
+	return nil
+}
+
+func main() {
+	// --- Argument Parsing ---
+	listenAddrStr := flag.String("listen", fmt.Sprintf(":%d", vban.DefaultPort), "Local address to listen on, or a multicast group to join")
+	bindAddrStr := flag.String("bind", "", "Local address to send from (default: the listening socket)")
+	configPath := flag.String("config", "", "JSON file with the routes (replaces the route flags)")
+	var route routeConfig
+	var to listFlag
+	flag.StringVar(&route.Stream, "stream", "", "Stream to forward (any stream if empty)")
+	flag.StringVar(&route.Source, "source", "", "Only forward packets from this IP address")
+	flag.StringVar(&route.Rename, "rename", "", "New stream name")
+	flag.BoolVar(&route.Renumber, "renumber", false, "Renumber the frame counter of forwarded packets")
+	flag.Var(&to, "to", "Destination address(es), comma-separated or repeated")
+	toFile := flag.String("to-file", "", "File with one destination address per line")
+	broadcast := flag.Bool("broadcast", false, "Allow broadcast destinations (e.g., 192.168.1.255:6980)")
+	readBuffer := flag.Int("rcvbuf", 0, "Socket receive buffer size in bytes (0 for the system default)")
+	dscp := flag.Int("dscp", -1, "DSCP value to mark forwarded packets with, e.g. 46 (EF) (-1 to leave unmarked)")
+	statsInterval := flag.Duration("stats", 0, "Log statistics at this interval (0 to disable)")
+	flag.Parse()
+
+	var routes []routeConfig
+	if *configPath != "" {
+		data, err := os.ReadFile(*configPath)
+		if err != nil {
+			log.Fatalf("Failed to read config '%s': %v", *configPath, err)
+		}
+		var cfg struct {
+			Routes []routeConfig `json:"routes"`
+		}
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			log.Fatalf("Failed to parse config '%s': %v", *configPath, err)
+		}
+		routes = cfg.Routes
+	} else {
+		if *toFile != "" {
+			lines, err := readLines(*toFile)
+			if err != nil {
+				log.Fatalf("Failed to read destinations from '%s': %v", *toFile, err)
+			}
+			to = append(to, lines...)
+		}
+		route.To = to
+		routes = []routeConfig{route}
+	}
+
+	relayCfg := vban.RelayConfig{
+		OnError: func(dst *net.UDPAddr, err error) { log.Printf("Warning: %s: %v", dst, err) },
+	}
+	for i, rc := range routes {
+		r, err := rc.route()
+		if err != nil {
+			log.Fatalf("Invalid route %d: %v", i+1, err)
+		}
+		relayCfg.Routes = append(relayCfg.Routes, r)
+	}
+
+	// --- VBAN Connection Setup ---
+	listenAddr, err := net.ResolveUDPAddr("udp", *listenAddrStr)
+	if err != nil {
+		log.Fatalf("Failed to resolve listen address '%s': %v", *listenAddrStr, err)
+	}
+	var inOpts, outOpts []vban.Option
+	if *readBuffer > 0 {
+		inOpts = append(inOpts, vban.WithReadBuffer(*readBuffer))
+	}
+	if *dscp >= 0 {
+		outOpts = append(outOpts, vban.WithDSCP(*dscp))
+	}
+	if *broadcast {
+		outOpts = append(outOpts, vban.WithBroadcast())
+	}
+	if *bindAddrStr == "" {
+		inOpts = append(inOpts, outOpts...) // The listening socket also sends
+	}
+	var in *vban.Conn
+	if listenAddr.IP.IsMulticast() {
+		in, err = vban.ListenMulticast(nil, listenAddr, inOpts...)
+	} else {
+		in, err = vban.Listen(listenAddr, inOpts...)
+	}
+	if err != nil {
+		log.Fatalf("Failed to listen on '%s': %v", listenAddr, err)
+	}
+	var out *vban.Conn
+	if *bindAddrStr != "" {
+		bindAddr, err := net.ResolveUDPAddr("udp", *bindAddrStr)
+		if err != nil {
+			log.Fatalf("Failed to resolve bind address '%s': %v", *bindAddrStr, err)
+		}
+		if out, err = vban.Listen(bindAddr, outOpts...); err != nil {
+			log.Fatalf("Failed to bind '%s': %v", bindAddr, err)
+		}
+		defer out.Close()
+	}
+
+	relay, err := vban.NewRelay(in, out, relayCfg)
+	if err != nil {
+		log.Fatalf("Failed to create relay: %v", err)
+	}
+	for i, r := range relayCfg.Routes {
+		name := r.StreamName
+		if name == "" {
+			name = "*"
+		}
+		log.Printf("Route %d: '%s' -> %d destination(s)", i+1, name, len(r.Destinations))
+	}
+	log.Printf("Relaying VBAN packets received on %s", in.LocalAddr())
+
+	// Close the connection on Ctrl-C to stop the relay.
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		<-sig
+		in.Close()
+	}()
+
+	if *statsInterval > 0 {
+		go func() {
+			for range time.Tick(*statsInterval) {
+				logStats(relay.Stats())
+			}
+		}()
+	}
+
+	if err := relay.Run(); err != nil {
+		log.Fatalf("Error receiving packets: %v", err)
+	}
+	logStats(relay.Stats())
+}
+
+// route converts the JSON form into a vban.RelayRoute.
+func (rc routeConfig) route() (vban.RelayRoute, error) {
+	r := vban.RelayRoute{StreamName: rc.Stream, Rename: rc.Rename, Renumber: rc.Renumber}
+	if rc.Source != "" {
+		ip := net.ParseIP(rc.Source)
+		if ip == nil {
+			return r, fmt.Errorf("invalid source IP address '%s'", rc.Source)
+		}
+		r.Source = &net.UDPAddr{IP: ip}
+	}
+	for _, p := range rc.Protocols {
+		switch strings.ToLower(p) {
+		case "audio":
+			r.Protocols = append(r.Protocols, vban.ProtocolAudio)
+		case "serial":
+			r.Protocols = append(r.Protocols, vban.ProtocolSerial)
+		case "text":
+			r.Protocols = append(r.Protocols, vban.ProtocolText)
+		case "service":
+			r.Protocols = append(r.Protocols, vban.ProtocolService)
+		default:
+			return r, fmt.Errorf("unknown sub-protocol '%s'", p)
+		}
+	}
+	if len(rc.To) == 0 {
+		return r, errors.New("no destinations (use -to, -to-file or \"to\")")
+	}
+	for _, s := range rc.To {
+		addr, err := net.ResolveUDPAddr("udp", s)
+		if err != nil {
+			return r, fmt.Errorf("failed to resolve destination '%s': %w", s, err)
+		}
+		r.Destinations = append(r.Destinations, addr)
+	}
+	return r, nil
+}
+
+// readLines returns the non-empty lines of a file, ignoring '#' comments.
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// logStats logs the relay counters.
+func logStats(s vban.RelayStats) {
+	log.Printf("Received: %d, matched: %d, forwarded: %d, errors: %d", s.Received, s.Matched, s.Forwarded, s.Errors)
+}
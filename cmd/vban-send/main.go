@@ -0,0 +1,142 @@
+// Command vban-send reads raw interleaved PCM from stdin and sends it as a VBAN audio
+// stream, e.g.:
+//
+//	arecord -f S16_LE -r 48000 -c 2 -t raw | vban-send -stream Stream1 -dest 192.168.1.10:6980
+//
+// Input read faster than real time (such as a file) is paced to the sample rate. The
+// destination may be a multicast group, in which case -ttl sets the number of router hops,
+// or a broadcast address with -broadcast.
+//
+// Usage:
+//
+//	vban-send [-dest 127.0.0.1:6980] [-stream name] [-rate 48000] [-channels 2] [-type int16] [-spp 0] [-pace=true]
+//	          [-ttl 0] [-broadcast] [-dscp 46] [-iface name]
+package main
+
+import (
+	"bufio"
+	"flag"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hrko/go-vban/vban"
+)
+
+const (
+	defaultStreamName = "Stream1"
+	defaultDestAddr   = "127.0.0.1:6980"
+)
+
+// dataTypes maps the -type flag values to VBAN data types.
+var dataTypes = map[string]vban.DataType{
+	"uint8":   vban.DataTypeUINT8,
+	"int16":   vban.DataTypeINT16,
+	"int24":   vban.DataTypeINT24,
+	"int32":   vban.DataTypeINT32,
+	"float32": vban.DataTypeFLOAT32,
+	"float64": vban.DataTypeFLOAT64,
+}
+
+func main() {
+	// --- Argument Parsing ---
+	destAddrStr := flag.String("dest", defaultDestAddr, "Destination address (e.g., 127.0.0.1:6980)")
+	streamName := flag.String("stream", defaultStreamName, "VBAN stream name")
+	rate := flag.Uint("rate", 48000, "Sample rate in Hz")
+	channels := flag.Int("channels", 2, "Number of interleaved channels")
+	typeName := flag.String("type", "int16", "Sample format: uint8, int16, int24, int32, float32 or float64 (little-endian)")
+	samplesPerPacket := flag.Int("spp", 0, "Samples per packet (0 for the largest that fits in a VBAN packet)")
+	pace := flag.Bool("pace", true, "Pace the output to the sample rate")
+	ttl := flag.Int("ttl", 0, "Multicast TTL (0 for the system default)")
+	broadcast := flag.Bool("broadcast", false, "Allow a broadcast destination (e.g., 192.168.1.255:6980)")
+	dscp := flag.Int("dscp", -1, "DSCP value to mark packets with, e.g. 46 (EF) (-1 to leave unmarked)")
+	iface := flag.String("iface", "", "Network interface to send from")
+	flag.Parse()
+
+	dataType, ok := dataTypes[strings.ToLower(*typeName)]
+	if !ok {
+		log.Fatalf("Unknown sample format '%s'", *typeName)
+	}
+	srIndex, err := vban.FindSRIndex(uint32(*rate))
+	if err != nil {
+		log.Fatalf("Failed to map sample rate to VBAN: %v", err)
+	}
+	format := vban.AudioFormat{SRIndex: srIndex, DataType: dataType, Channels: *channels}
+
+	// --- VBAN Connection Setup ---
+	destAddr, err := net.ResolveUDPAddr("udp", *destAddrStr)
+	if err != nil {
+		log.Fatalf("Failed to resolve destination address '%s': %v", *destAddrStr, err)
+	}
+	var opts []vban.Option
+	if *dscp >= 0 {
+		opts = append(opts, vban.WithDSCP(*dscp))
+	}
+	if *iface != "" {
+		opts = append(opts, vban.WithInterface(*iface))
+	}
+	var conn *vban.Conn
+	if *broadcast {
+		conn, err = vban.DialBroadcast(nil, destAddr, opts...)
+	} else {
+		conn, err = vban.Dial(nil, destAddr, opts...) // Use nil for local address (OS chooses)
+	}
+	if err != nil {
+		log.Fatalf("Failed to dial VBAN destination '%s': %v", destAddr, err)
+	}
+	defer conn.Close()
+	if destAddr.IP.IsMulticast() && *ttl != 0 {
+		if err := conn.SetMulticastTTL(*ttl); err != nil {
+			log.Fatalf("Failed to set multicast TTL: %v", err)
+		}
+	}
+
+	writer, err := vban.NewAudioStreamWriter(conn, nil, *streamName, format)
+	if err != nil {
+		log.Fatalf("Failed to create stream writer: %v", err)
+	}
+	if *samplesPerPacket != 0 {
+		if err := writer.SetSamplesPerPacket(*samplesPerPacket); err != nil {
+			log.Fatalf("Invalid samples per packet: %v", err)
+		}
+	}
+	log.Printf("Sending VBAN stream '%s' to %s from %s (%d Hz, %d channel(s), %s, %d samples per packet)",
+		*streamName, conn.RemoteAddr(), conn.LocalAddr(), *rate, *channels, dataType, writer.SamplesPerPacket())
+
+	// --- Transmission Loop ---
+	frameSize := format.FrameBits() / 8
+	buf := make([]byte, writer.SamplesPerPacket()*frameSize)
+	in := bufio.NewReaderSize(os.Stdin, 64<<10)
+	startTime := time.Now()
+	var framesSent int64
+	for {
+		n, err := io.ReadFull(in, buf)
+		n -= n % frameSize
+		if n > 0 {
+			if _, werr := writer.Write(buf[:n]); werr != nil {
+				log.Printf("Warning: %v", werr)
+			}
+			framesSent += int64(n / frameSize)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			log.Fatalf("Error reading stdin: %v", err)
+		}
+
+		// --- Rate Control ---
+		if *pace {
+			expected := time.Duration(float64(framesSent) / float64(*rate) * float64(time.Second))
+			time.Sleep(max(expected-time.Since(startTime), 0))
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	log.Printf("Finished sending %d frames (%v).", framesSent,
+		time.Duration(float64(framesSent)/float64(*rate)*float64(time.Second)).Round(time.Millisecond))
+}
@@ -2,10 +2,11 @@ module github.com/hrko/go-vban/example/sendwav
 
 go 1.24.2
 
+require github.com/hrko/go-vban v0.0.2-0.20250417114830-37138c425550
+
 require (
-	github.com/go-audio/audio v1.0.0
-	github.com/go-audio/wav v1.1.0
-	github.com/hrko/go-vban v0.0.2-0.20250417114830-37138c425550
+	golang.org/x/net v0.44.0 // indirect
+	golang.org/x/sys v0.36.0 // indirect
 )
 
-require github.com/go-audio/riff v1.0.0 // indirect
+replace github.com/hrko/go-vban => ../..
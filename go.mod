@@ -1,3 +1,8 @@
 module github.com/hrko/go-vban
 
 go 1.24.2
+
+require (
+	golang.org/x/net v0.44.0
+	golang.org/x/sys v0.36.0
+)
@@ -0,0 +1,331 @@
+package vban
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// --- Audio Sample Codec (Spec p.9) ---
+//
+// The functions below convert between the raw little-endian payload of a VBAN
+// audio packet and interleaved sample buffers. Two sample representations are
+// supported:
+//
+//   - float32: normalized to the range [-1.0, +1.0).
+//   - int32:   MSB-aligned to the full int32 range, so that a sample keeps the
+//     same magnitude regardless of the wire format (e.g. INT16 0x1234 becomes
+//     0x12340000, UINT8 0x80 becomes 0).
+//
+// The bit-packed 12BIT and 10BIT types are stored as a continuous little-endian
+// bit stream: sample i occupies bits [i*n, i*n+n) of the payload, starting at the
+// least significant bit of the first byte.
+
+// AudioFormat describes the layout of an uncompressed (PCM) VBAN audio stream.
+type AudioFormat struct {
+	SRIndex  SRIndex  // Sample rate index (see SRList)
+	DataType DataType // Sample encoding
+	Channels int      // Number of interleaved channels (1-256)
+}
+
+// SampleRate returns the sample rate in Hz, or 0 if the SRIndex is undefined.
+func (f AudioFormat) SampleRate() uint32 {
+	return f.SRIndex.GetRate(ProtocolAudio)
+}
+
+// FrameBits returns the number of bits occupied by one frame (one sample for every channel).
+func (f AudioFormat) FrameBits() int {
+	return f.Channels * f.DataType.BitsPerSample()
+}
+
+// validate checks that the format can be expressed in a VBAN audio header.
+func (f AudioFormat) validate() error {
+	if f.Channels < 1 || f.Channels > 256 {
+		return fmt.Errorf("number of channels must be between 1 and 256, got %d", f.Channels)
+	}
+	if f.SampleRate() == 0 {
+		return fmt.Errorf("undefined sample rate index: %d", f.SRIndex)
+	}
+	return nil
+}
+
+// AudioFormat returns the PCM stream layout described by an audio header.
+func (h *Header) AudioFormat() AudioFormat {
+	return AudioFormat{
+		SRIndex:  h.SRIndex(),
+		DataType: h.DataType(),
+		Channels: int(h.FormatNbc) + 1, // Channels() wraps to 0 for 256 channels
+	}
+}
+
+// decodeSampleCount returns how many samples can be decoded from src into a
+// destination holding dstLen samples.
+func decodeSampleCount(dt DataType, dstLen int, src []byte) int {
+	return min(dstLen, dt.SampleCount(len(src)))
+}
+
+// encodeSampleCount returns how many samples from a source holding srcLen
+// samples fit into dst.
+func encodeSampleCount(dt DataType, srcLen int, dst []byte) int {
+	return min(srcLen, dt.SampleCount(len(dst)))
+}
+
+// readPacked extracts the i-th signed sample of the given bit width from a
+// little-endian bit stream.
+func readPacked(src []byte, i, bits int) int32 {
+	off := i * bits
+	pos := off >> 3
+	end := (off + bits + 7) >> 3
+	var v uint32
+	for j := pos; j < end; j++ {
+		v |= uint32(src[j]) << (8 * (j - pos))
+	}
+	v >>= uint(off & 7)
+	// Sign-extend from the sample width to 32 bits
+	return int32(v<<(32-bits)) >> (32 - bits)
+}
+
+// writePacked stores the i-th sample of the given bit width into a
+// little-endian bit stream. The affected bits must be zero beforehand.
+func writePacked(dst []byte, i, bits int, sample int32) {
+	off := i * bits
+	pos := off >> 3
+	end := (off + bits + 7) >> 3
+	v := (uint32(sample) & (1<<bits - 1)) << uint(off&7)
+	for j := pos; j < end; j++ {
+		dst[j] |= byte(v >> (8 * (j - pos)))
+	}
+}
+
+// floatToInt converts a normalized float sample to a signed integer of the
+// given bit width, rounding to nearest and clipping out-of-range values.
+func floatToInt(f float64, bits int) int32 {
+	scale := float64(int64(1) << (bits - 1))
+	v := math.Round(f * scale)
+	if v >= scale {
+		return int32(scale - 1)
+	}
+	if v < -scale {
+		return int32(-scale)
+	}
+	return int32(v)
+}
+
+// DecodeFloat32 decodes samples of the given DataType from the raw payload src
+// into dst as normalized float32 values. It decodes as many whole samples as
+// fit into both buffers and returns the number of samples written.
+func DecodeFloat32(dt DataType, dst []float32, src []byte) int {
+	n := decodeSampleCount(dt, len(dst), src)
+	switch dt & DataTypeMask {
+	case DataTypeUINT8:
+		for i := range n {
+			dst[i] = float32(int32(src[i])-0x80) / (1 << 7)
+		}
+	case DataTypeINT16:
+		for i := range n {
+			dst[i] = float32(int16(byteOrder.Uint16(src[2*i:]))) / (1 << 15)
+		}
+	case DataTypeINT24:
+		for i := range n {
+			b := src[3*i : 3*i+3]
+			v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+			dst[i] = float32(v) / (1 << 23)
+		}
+	case DataTypeINT32:
+		for i := range n {
+			dst[i] = float32(float64(int32(byteOrder.Uint32(src[4*i:]))) / (1 << 31))
+		}
+	case DataTypeFLOAT32:
+		for i := range n {
+			dst[i] = math.Float32frombits(byteOrder.Uint32(src[4*i:]))
+		}
+	case DataTypeFLOAT64:
+		for i := range n {
+			dst[i] = float32(math.Float64frombits(byteOrder.Uint64(src[8*i:])))
+		}
+	case DataType12BIT, DataType10BIT:
+		bits := dt.BitsPerSample()
+		scale := float32(int32(1) << (bits - 1))
+		for i := range n {
+			dst[i] = float32(readPacked(src, i, bits)) / scale
+		}
+	}
+	return n
+}
+
+// DecodeInt32 decodes samples of the given DataType from the raw payload src
+// into dst as MSB-aligned int32 values. Float samples are clipped to [-1.0, +1.0).
+// It decodes as many whole samples as fit into both buffers and returns the
+// number of samples written.
+func DecodeInt32(dt DataType, dst []int32, src []byte) int {
+	n := decodeSampleCount(dt, len(dst), src)
+	switch dt & DataTypeMask {
+	case DataTypeUINT8:
+		for i := range n {
+			dst[i] = (int32(src[i]) - 0x80) << 24
+		}
+	case DataTypeINT16:
+		for i := range n {
+			dst[i] = int32(int16(byteOrder.Uint16(src[2*i:]))) << 16
+		}
+	case DataTypeINT24:
+		for i := range n {
+			b := src[3*i : 3*i+3]
+			dst[i] = int32(uint32(b[0])<<8 | uint32(b[1])<<16 | uint32(b[2])<<24)
+		}
+	case DataTypeINT32:
+		for i := range n {
+			dst[i] = int32(byteOrder.Uint32(src[4*i:]))
+		}
+	case DataTypeFLOAT32:
+		for i := range n {
+			dst[i] = floatToInt(float64(math.Float32frombits(byteOrder.Uint32(src[4*i:]))), 32)
+		}
+	case DataTypeFLOAT64:
+		for i := range n {
+			dst[i] = floatToInt(math.Float64frombits(byteOrder.Uint64(src[8*i:])), 32)
+		}
+	case DataType12BIT, DataType10BIT:
+		bits := dt.BitsPerSample()
+		for i := range n {
+			dst[i] = readPacked(src, i, bits) << (32 - bits)
+		}
+	}
+	return n
+}
+
+// EncodeFloat32 encodes normalized float32 samples from src into dst using the
+// given DataType. Values outside [-1.0, +1.0) are clipped for integer types.
+// It encodes as many samples as fit into dst and returns the number of bytes
+// written (see DataType.PayloadSize).
+func EncodeFloat32(dt DataType, dst []byte, src []float32) int {
+	n := encodeSampleCount(dt, len(src), dst)
+	switch dt & DataTypeMask {
+	case DataTypeUINT8:
+		for i := range n {
+			dst[i] = byte(floatToInt(float64(src[i]), 8) + 0x80)
+		}
+	case DataTypeINT16:
+		for i := range n {
+			byteOrder.PutUint16(dst[2*i:], uint16(floatToInt(float64(src[i]), 16)))
+		}
+	case DataTypeINT24:
+		for i := range n {
+			v := floatToInt(float64(src[i]), 24)
+			dst[3*i], dst[3*i+1], dst[3*i+2] = byte(v), byte(v>>8), byte(v>>16)
+		}
+	case DataTypeINT32:
+		for i := range n {
+			byteOrder.PutUint32(dst[4*i:], uint32(floatToInt(float64(src[i]), 32)))
+		}
+	case DataTypeFLOAT32:
+		for i := range n {
+			byteOrder.PutUint32(dst[4*i:], math.Float32bits(src[i]))
+		}
+	case DataTypeFLOAT64:
+		for i := range n {
+			byteOrder.PutUint64(dst[8*i:], math.Float64bits(float64(src[i])))
+		}
+	case DataType12BIT, DataType10BIT:
+		bits := dt.BitsPerSample()
+		clear(dst[:dt.PayloadSize(n)])
+		for i := range n {
+			writePacked(dst, i, bits, floatToInt(float64(src[i]), bits))
+		}
+	}
+	return dt.PayloadSize(n)
+}
+
+// EncodeInt32 encodes MSB-aligned int32 samples from src into dst using the
+// given DataType. Samples are truncated to the target resolution.
+// It encodes as many samples as fit into dst and returns the number of bytes
+// written (see DataType.PayloadSize).
+func EncodeInt32(dt DataType, dst []byte, src []int32) int {
+	n := encodeSampleCount(dt, len(src), dst)
+	switch dt & DataTypeMask {
+	case DataTypeUINT8:
+		for i := range n {
+			dst[i] = byte(src[i]>>24 + 0x80)
+		}
+	case DataTypeINT16:
+		for i := range n {
+			byteOrder.PutUint16(dst[2*i:], uint16(src[i]>>16))
+		}
+	case DataTypeINT24:
+		for i := range n {
+			v := src[i] >> 8
+			dst[3*i], dst[3*i+1], dst[3*i+2] = byte(v), byte(v>>8), byte(v>>16)
+		}
+	case DataTypeINT32:
+		for i := range n {
+			byteOrder.PutUint32(dst[4*i:], uint32(src[i]))
+		}
+	case DataTypeFLOAT32:
+		for i := range n {
+			byteOrder.PutUint32(dst[4*i:], math.Float32bits(float32(float64(src[i])/(1<<31))))
+		}
+	case DataTypeFLOAT64:
+		for i := range n {
+			byteOrder.PutUint64(dst[8*i:], math.Float64bits(float64(src[i])/(1<<31)))
+		}
+	case DataType12BIT, DataType10BIT:
+		bits := dt.BitsPerSample()
+		clear(dst[:dt.PayloadSize(n)])
+		for i := range n {
+			writePacked(dst, i, bits, src[i]>>(32-bits))
+		}
+	}
+	return dt.PayloadSize(n)
+}
+
+// --- Packet Helpers ---
+
+// AudioSamples returns the total number of samples (SamplesPerFrame * Channels)
+// announced by the header of an audio packet.
+func (p *Packet) AudioSamples() int {
+	// Computed from the raw fields, as the uint8 accessors wrap to 0 for 256.
+	return (int(p.Header.FormatNbs) + 1) * (int(p.Header.FormatNbc) + 1)
+}
+
+// audioPayload returns the part of the payload holding the announced samples.
+// It fails for non-audio packets, compressed codecs and truncated payloads.
+func (p *Packet) audioPayload() ([]byte, error) {
+	if !p.Header.SubProtocol().IsAudio() {
+		return nil, errors.New("packet is not an audio packet")
+	}
+	if codec := p.Header.CodecType(); codec != CodecPCM {
+		return nil, fmt.Errorf("unsupported audio codec: 0x%02X", uint8(codec))
+	}
+	size := p.Header.DataType().PayloadSize(p.AudioSamples())
+	if len(p.Data) < size {
+		return nil, fmt.Errorf("audio payload too short: got %d bytes, header announces %d", len(p.Data), size)
+	}
+	return p.Data[:size], nil
+}
+
+// Float32Samples decodes the PCM payload of an audio packet and appends the
+// interleaved samples to dst as normalized float32 values.
+func (p *Packet) Float32Samples(dst []float32) ([]float32, error) {
+	payload, err := p.audioPayload()
+	if err != nil {
+		return dst, err
+	}
+	n := len(dst)
+	dst = slices.Grow(dst, p.AudioSamples())[:n+p.AudioSamples()]
+	DecodeFloat32(p.Header.DataType(), dst[n:], payload)
+	return dst, nil
+}
+
+// Int32Samples decodes the PCM payload of an audio packet and appends the
+// interleaved samples to dst as MSB-aligned int32 values.
+func (p *Packet) Int32Samples(dst []int32) ([]int32, error) {
+	payload, err := p.audioPayload()
+	if err != nil {
+		return dst, err
+	}
+	n := len(dst)
+	dst = slices.Grow(dst, p.AudioSamples())[:n+p.AudioSamples()]
+	DecodeInt32(p.Header.DataType(), dst[n:], payload)
+	return dst, nil
+}
@@ -0,0 +1,426 @@
+package vban
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultReceiverLatency is the jitter-buffer latency used when AudioReceiverConfig.Latency is zero.
+const DefaultReceiverLatency = 40 * time.Millisecond
+
+// LossConcealment selects how an AudioReceiver fills frames that were lost on the network.
+type LossConcealment int
+
+const (
+	ConcealSilence LossConcealment = iota // Replace a lost frame with digital silence
+	ConcealRepeat                         // Repeat the previously played frame
+)
+
+// AudioReceiverConfig configures an AudioReceiver.
+type AudioReceiverConfig struct {
+	StreamName  string          // Name of the stream to receive (required)
+	Source      *net.UDPAddr    // Optional: only accept packets from this IP (and port, if non-zero)
+	Latency     time.Duration   // Target jitter-buffer latency (DefaultReceiverLatency if zero)
+	Concealment LossConcealment // How lost frames are filled
+
+	// OnGap, if set, is called from the reading goroutine once per gap, when the count
+	// frames starting at NuFrame first could not be received in time and are concealed.
+	// It is called after Read or ReadFloat32 has released the receiver's lock.
+	OnGap func(first uint32, count int)
+}
+
+// receiverGap is a run of lost frames waiting to be reported to OnGap.
+type receiverGap struct {
+	first uint32
+	count int
+}
+
+// ReceiverStats holds the packet counters of an AudioReceiver.
+type ReceiverStats struct {
+	Received   uint64 // Packets accepted into the jitter buffer
+	Played     uint64 // Frames handed out to the reader (excluding concealed frames)
+	Lost       uint64 // Frames not received in time and concealed
+	Duplicates uint64 // Packets received more than once
+	Late       uint64 // Packets arriving after their frame was already played out
+	Reordered  uint64 // Packets arriving out of order but still in time
+	Overflows  uint64 // Frames dropped because the buffer grew beyond its limit
+	Resyncs    uint64 // Buffer resets caused by format changes or frame counter jumps
+}
+
+// AudioReceiver reassembles one incoming VBAN audio stream into a continuous PCM stream.
+//
+// Packets are fed through HandlePacket (or read from a Conn by Run), filtered by stream
+// name and source, and stored in a jitter buffer keyed by NuFrame. Late packets are
+// reordered, duplicates are dropped, and frames still missing once the buffer holds more
+// than the target latency are concealed according to the configured LossConcealment.
+//
+// The reassembled stream is read with Read (raw samples in the stream's DataType) or
+// ReadFloat32 (normalized samples). Both block until data is available. Use only one of
+// the two read methods on a given receiver.
+type AudioReceiver struct {
+	conn *Conn
+	cfg  AudioReceiverConfig
+
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	hasFormat bool
+	format    AudioFormat
+	spf       int // Samples per frame (per channel) of the current stream
+	target    int // Target buffer depth in packets
+	maxDepth  int // Depth at which old frames are discarded
+
+	frames  map[uint32][]byte // Jitter buffer: payloads keyed by NuFrame
+	next    uint32            // NuFrame of the next frame to play out
+	newest  uint32            // Highest NuFrame received so far
+	playing bool              // Prebuffering is complete
+
+	concealing int           // Concealed frames still to be played out before next
+	gaps       []receiverGap // Gaps found by the current read, reported once r.mu is released
+
+	cur     []byte    // Remainder of the frame being read by Read
+	fcur    []float32 // Remainder of the frame being read by ReadFloat32
+	fbuf    []float32 // Decoding buffer backing fcur
+	prev    []byte    // Last frame played out (for ConcealRepeat)
+	silence []byte    // Silent frame for the current format
+
+	drift  *DriftEstimator // Sender clock estimate, reset with the buffer
+	stats  ReceiverStats
+	closed bool
+}
+
+// NewAudioReceiver creates a receiver for the stream named in cfg.
+// conn may be nil if packets are delivered through HandlePacket only (e.g. by a Mux).
+func NewAudioReceiver(conn *Conn, cfg AudioReceiverConfig) (*AudioReceiver, error) {
+	if cfg.StreamName == "" {
+		return nil, errors.New("stream name is required")
+	}
+	if cfg.Latency <= 0 {
+		cfg.Latency = DefaultReceiverLatency
+	}
+	r := &AudioReceiver{
+		conn:   conn,
+		cfg:    cfg,
+		frames: make(map[uint32][]byte),
+	}
+	r.cond = sync.NewCond(&r.mu)
+	return r, nil
+}
+
+// matchSource reports whether addr matches the filter. A nil filter matches any address;
+// a zero filter port matches any port.
+func matchSource(filter, addr *net.UDPAddr) bool {
+	if filter == nil {
+		return true
+	}
+	if addr == nil || !filter.IP.Equal(addr.IP) {
+		return false
+	}
+	return filter.Port == 0 || filter.Port == addr.Port
+}
+
+// Run reads packets from the receiver's Conn and feeds them into the jitter buffer until the
+// connection is closed (returning nil) or fails. Malformed packets are skipped. The receiver
+// is closed when Run returns.
+func (r *AudioReceiver) Run() error {
+	if r.conn == nil {
+		return errors.New("receiver has no connection")
+	}
+	defer r.Close()
+	return serve(r.conn, r)
+}
+
+// HandlePacket feeds a received packet into the jitter buffer. Packets that do not belong to
+// the configured stream are ignored. The packet's payload is retained by the receiver.
+func (r *AudioReceiver) HandlePacket(p *Packet, addr *net.UDPAddr) {
+	if p == nil || !p.Header.SubProtocol().IsAudio() || p.Header.CodecType() != CodecPCM {
+		return
+	}
+	if p.Header.GetStreamName() != r.cfg.StreamName || !matchSource(r.cfg.Source, addr) {
+		return
+	}
+	payload, err := p.audioPayload()
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+
+	format := p.Header.AudioFormat()
+	spf := int(p.Header.FormatNbs) + 1
+	nu := p.Header.NuFrame
+	if !r.hasFormat || format != r.format || spf != r.spf {
+		r.reset(format, spf, nu)
+	}
+
+	d := int32(nu - r.next)
+	switch {
+	case d < -int32(r.maxDepth) || d > 8*int32(r.maxDepth):
+		// The sender restarted or the stream paused for a long time.
+		r.reset(format, spf, nu)
+	case d < 0:
+		r.stats.Late++
+		return
+	}
+	if _, ok := r.frames[nu]; ok {
+		r.stats.Duplicates++
+		return
+	}
+	r.frames[nu] = payload
+	r.stats.Received++
+	r.drift.Update(nu, spf, time.Now())
+	if int32(nu-r.newest) >= 0 {
+		r.newest = nu
+	} else {
+		r.stats.Reordered++
+	}
+
+	// Discard the oldest frames if the reader is not keeping up.
+	if r.depth() > r.maxDepth {
+		for r.depth() > r.target {
+			if _, ok := r.frames[r.next]; ok {
+				delete(r.frames, r.next)
+			}
+			r.next++
+			r.stats.Overflows++
+		}
+	}
+	r.cond.Broadcast()
+}
+
+// reset discards the buffer and restarts prebuffering for a (possibly new) stream format.
+// It must be called with r.mu held.
+func (r *AudioReceiver) reset(format AudioFormat, spf int, nu uint32) {
+	if r.hasFormat {
+		r.stats.Resyncs++
+	}
+	r.hasFormat = true
+	r.format = format
+	r.spf = spf
+	rate := format.SampleRate()
+	r.target = max(1, int((r.cfg.Latency.Seconds()*float64(rate)+float64(spf)-1)/float64(spf)))
+	r.maxDepth = max(r.target+2, 3*r.target)
+	clear(r.frames)
+	r.next, r.newest = nu, nu
+	r.playing = false
+	r.concealing = 0
+	r.cur, r.fcur, r.prev = nil, nil, nil
+	r.drift = NewDriftEstimator(rate, 0)
+
+	r.silence = make([]byte, format.DataType.PayloadSize(spf*format.Channels))
+	if format.DataType == DataTypeUINT8 {
+		for i := range r.silence {
+			r.silence[i] = 0x80 // Unsigned 8-bit silence is the mid value
+		}
+	}
+}
+
+// depth returns the number of frames between the play-out position and the newest
+// received frame, including missing ones. It must be called with r.mu held.
+func (r *AudioReceiver) depth() int {
+	if len(r.frames) == 0 {
+		return 0
+	}
+	return int(int32(r.newest-r.next)) + 1
+}
+
+// nextFrame returns the payload of the next frame to play out, concealing lost frames as
+// needed. If block is false it returns nil instead of waiting. It returns io.EOF once the
+// receiver is closed. It must be called with r.mu held.
+func (r *AudioReceiver) nextFrame(block bool) ([]byte, error) {
+	for {
+		if r.closed {
+			return nil, io.EOF
+		}
+		if r.hasFormat {
+			if !r.playing && len(r.frames) >= r.target {
+				r.playing = true
+			}
+			if r.playing {
+				if r.concealing > 0 {
+					return r.conceal(), nil
+				}
+				if frame, ok := r.frames[r.next]; ok {
+					delete(r.frames, r.next)
+					r.next++
+					r.prev = frame
+					r.stats.Played++
+					return frame, nil
+				}
+				if r.depth() > r.target {
+					return r.conceal(), nil
+				}
+				if len(r.frames) == 0 {
+					// Buffer ran dry: rebuild the target latency before resuming.
+					r.playing = false
+				}
+			}
+		}
+		if !block {
+			return nil, nil
+		}
+		r.cond.Wait()
+	}
+}
+
+// conceal returns a replacement for the next lost frame. At the start of a gap, it skips
+// the missing frames up to the next received one and records the gap for OnGap. It must
+// be called with r.mu held.
+func (r *AudioReceiver) conceal() []byte {
+	if r.concealing == 0 {
+		first := r.next
+		count := 1
+		for depth := r.depth(); count < depth; count++ {
+			if _, ok := r.frames[first+uint32(count)]; ok {
+				break
+			}
+		}
+		r.next += uint32(count)
+		r.concealing = count
+		r.stats.Lost += uint64(count)
+		if r.cfg.OnGap != nil {
+			r.gaps = append(r.gaps, receiverGap{first: first, count: count})
+		}
+	}
+	r.concealing--
+	if r.cfg.Concealment == ConcealRepeat && r.prev != nil {
+		return r.prev
+	}
+	return r.silence
+}
+
+// unlock releases r.mu and then reports the gaps found while it was held to OnGap.
+func (r *AudioReceiver) unlock() {
+	gaps := r.gaps
+	r.gaps = nil
+	r.mu.Unlock()
+	for _, g := range gaps {
+		r.cfg.OnGap(g.first, g.count)
+	}
+}
+
+// Read implements io.Reader, returning raw interleaved samples encoded in the stream's
+// DataType. It blocks until at least one frame is available and returns io.EOF once the
+// receiver is closed. For bit-packed data types a frame may end mid-byte; Read hands out
+// each frame's payload as sent.
+func (r *AudioReceiver) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.unlock()
+	n := 0
+	for n < len(p) {
+		if len(r.cur) == 0 {
+			frame, err := r.nextFrame(n == 0)
+			if err != nil {
+				return n, err
+			}
+			if frame == nil {
+				break
+			}
+			r.cur = frame
+		}
+		k := copy(p[n:], r.cur)
+		r.cur = r.cur[k:]
+		n += k
+	}
+	return n, nil
+}
+
+// ReadFloat32 reads normalized interleaved samples into dst. It blocks until at least one
+// frame is available and returns the number of samples read, or io.EOF once the receiver
+// is closed.
+func (r *AudioReceiver) ReadFloat32(dst []float32) (int, error) {
+	r.mu.Lock()
+	defer r.unlock()
+	n := 0
+	for n < len(dst) {
+		if len(r.fcur) == 0 {
+			frame, err := r.nextFrame(n == 0)
+			if err != nil {
+				return n, err
+			}
+			if frame == nil {
+				break
+			}
+			samples := r.spf * r.format.Channels
+			if cap(r.fbuf) < samples {
+				r.fbuf = make([]float32, samples)
+			}
+			r.fcur = r.fbuf[:DecodeFloat32(r.format.DataType, r.fbuf[:samples], frame)]
+		}
+		k := copy(dst[n:], r.fcur)
+		r.fcur = r.fcur[k:]
+		n += k
+	}
+	return n, nil
+}
+
+// Format returns the format of the received stream. ok is false until the first packet
+// has been received.
+func (r *AudioReceiver) Format() (format AudioFormat, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.format, r.hasFormat
+}
+
+// waitFormat blocks until the first packet has been received and returns the stream
+// format. ok is false if the receiver was closed first.
+func (r *AudioReceiver) waitFormat() (format AudioFormat, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for !r.hasFormat && !r.closed {
+		r.cond.Wait()
+	}
+	return r.format, r.hasFormat
+}
+
+// BufferLevel returns the number of sample frames (samples per channel) currently
+// buffered and the target level derived from the configured latency. Both are 0 until
+// the first packet has been received.
+func (r *AudioReceiver) BufferLevel() (buffered, target int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.hasFormat {
+		return 0, 0
+	}
+	buffered = (len(r.frames)+r.concealing)*r.spf + len(r.fcur)/r.format.Channels
+	if bits := r.format.FrameBits(); bits > 0 {
+		buffered += len(r.cur) * 8 / bits
+	}
+	return buffered, r.target * r.spf
+}
+
+// Drift returns the estimated rate of the sender's clock relative to its nominal sample
+// rate, e.g. 1.0001 for a sender running 100 ppm fast. It returns 1 until enough packets
+// have been received for an estimate.
+func (r *AudioReceiver) Drift() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.drift == nil {
+		return 1
+	}
+	return r.drift.Ratio()
+}
+
+// Stats returns a snapshot of the receiver's packet counters.
+func (r *AudioReceiver) Stats() ReceiverStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+// Close stops the receiver. Pending and future reads return io.EOF.
+// It does not close the underlying Conn.
+func (r *AudioReceiver) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	r.cond.Broadcast()
+	return nil
+}
@@ -0,0 +1,166 @@
+package vban
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"testing"
+)
+
+var allDataTypes = []DataType{
+	DataTypeUINT8, DataTypeINT16, DataTypeINT24, DataTypeINT32,
+	DataTypeFLOAT32, DataTypeFLOAT64, DataType12BIT, DataType10BIT,
+}
+
+// Sample counts covering single samples and bit-packed payloads that end mid-byte.
+var testSampleCounts = []int{1, 2, 3, 5, 7, 255}
+
+// exactBits returns the number of significant bits of an MSB-aligned int32 sample that
+// survive a round trip through dt.
+func exactBits(dt DataType) int {
+	switch dt {
+	case DataTypeFLOAT32:
+		return 24 // float32 mantissa
+	case DataTypeFLOAT64:
+		return 32
+	default:
+		return dt.BitsPerSample()
+	}
+}
+
+func TestInt32RoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for _, dt := range allDataTypes {
+		for _, n := range testSampleCounts {
+			mask := int32(-1) << (32 - exactBits(dt))
+			src := make([]int32, n)
+			for i := range src {
+				src[i] = int32(rng.Uint32()) & mask
+			}
+			src[0] = -1 << 31 // Full-scale negative
+
+			payload := make([]byte, dt.PayloadSize(n))
+			if got := EncodeInt32(dt, payload, src); got != len(payload) {
+				t.Fatalf("%v, %d samples: EncodeInt32 wrote %d bytes, want %d", dt, n, got, len(payload))
+			}
+			dst := make([]int32, n)
+			if got := DecodeInt32(dt, dst, payload); got != n {
+				t.Fatalf("%v, %d samples: DecodeInt32 decoded %d samples, want %d", dt, n, got, n)
+			}
+			for i := range src {
+				if dst[i] != src[i] {
+					t.Errorf("%v, %d samples: sample %d: got %#x, want %#x", dt, n, i, dst[i], src[i])
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestFloat32RoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	for _, dt := range allDataTypes {
+		bits := min(exactBits(dt), 24) // Beyond 24 bits, float32 itself is the limit
+		scale := float32(int32(1) << (bits - 1))
+		for _, n := range testSampleCounts {
+			src := make([]float32, n)
+			for i := range src {
+				src[i] = float32(rng.Int32N(1<<bits)-1<<(bits-1)) / scale
+			}
+			src[0] = -1
+			if n > 1 {
+				src[1] = (scale - 1) / scale // Full-scale positive
+			}
+
+			payload := make([]byte, dt.PayloadSize(n))
+			if got := EncodeFloat32(dt, payload, src); got != len(payload) {
+				t.Fatalf("%v, %d samples: EncodeFloat32 wrote %d bytes, want %d", dt, n, got, len(payload))
+			}
+			dst := make([]float32, n)
+			if got := DecodeFloat32(dt, dst, payload); got != n {
+				t.Fatalf("%v, %d samples: DecodeFloat32 decoded %d samples, want %d", dt, n, got, n)
+			}
+			for i := range src {
+				if dst[i] != src[i] {
+					t.Errorf("%v, %d samples: sample %d: got %v, want %v", dt, n, i, dst[i], src[i])
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeFloat32Clips(t *testing.T) {
+	for _, dt := range []DataType{DataTypeUINT8, DataTypeINT16, DataTypeINT24, DataTypeINT32, DataType12BIT, DataType10BIT} {
+		payload := make([]byte, dt.PayloadSize(2))
+		EncodeFloat32(dt, payload, []float32{1.5, -1.5})
+		dst := make([]int32, 2)
+		DecodeInt32(dt, dst, payload)
+		top := int32(-1<<31) ^ -1<<(32-dt.BitsPerSample()) // Largest MSB-aligned value
+		if dst[0] != top || dst[1] != -1<<31 {
+			t.Errorf("%v: got %#x, %#x; want %#x, %#x", dt, dst[0], dst[1], top, int32(-1<<31))
+		}
+	}
+}
+
+func TestPackedLayout(t *testing.T) {
+	tests := []struct {
+		dt      DataType
+		samples []int32 // At the type's resolution
+		want    []byte
+	}{
+		// 12-bit samples 0x123, -1 and 0x456 as a little-endian bit stream; the last
+		// byte holds the top 4 bits of the third sample and 4 bits of zero padding.
+		{DataType12BIT, []int32{0x123, -1, 0x456}, []byte{0x23, 0xF1, 0xFF, 0x56, 0x04}},
+		// 10-bit samples 0x155, -0x200 and 0x001: 30 bits in 4 bytes.
+		{DataType10BIT, []int32{0x155, -0x200, 0x001}, []byte{0x55, 0x01, 0x18, 0x00}},
+		{DataTypeINT24, []int32{0x123456, -2}, []byte{0x56, 0x34, 0x12, 0xFE, 0xFF, 0xFF}},
+	}
+	for _, tt := range tests {
+		bits := tt.dt.BitsPerSample()
+		src := make([]int32, len(tt.samples))
+		for i, s := range tt.samples {
+			src[i] = s << (32 - bits)
+		}
+		dst := bytes.Repeat([]byte{0xAA}, len(tt.want)+1)
+		if n := EncodeInt32(tt.dt, dst, src); n != len(tt.want) {
+			t.Fatalf("%v: EncodeInt32 wrote %d bytes, want %d", tt.dt, n, len(tt.want))
+		}
+		if !bytes.Equal(dst[:len(tt.want)], tt.want) {
+			t.Errorf("%v: encoded % X, want % X", tt.dt, dst[:len(tt.want)], tt.want)
+		}
+		if dst[len(tt.want)] != 0xAA {
+			t.Errorf("%v: EncodeInt32 wrote past the payload", tt.dt)
+		}
+
+		got := make([]int32, len(tt.samples))
+		if n := DecodeInt32(tt.dt, got, tt.want); n != len(tt.samples) {
+			t.Fatalf("%v: DecodeInt32 decoded %d samples, want %d", tt.dt, n, len(tt.samples))
+		}
+		for i := range got {
+			if got[i]>>(32-bits) != tt.samples[i] {
+				t.Errorf("%v: sample %d: got %#x, want %#x", tt.dt, i, got[i]>>(32-bits), tt.samples[i])
+			}
+		}
+	}
+}
+
+func TestDecodePartialPayload(t *testing.T) {
+	// 10 bytes hold 8 whole 10-bit samples; the last partial sample is not decoded.
+	dst := make([]float32, 16)
+	if n := DecodeFloat32(DataType10BIT, dst, make([]byte, 10)); n != 8 {
+		t.Errorf("DecodeFloat32 decoded %d samples from 10 bytes of 10BIT, want 8", n)
+	}
+	// The destination limits the count as well.
+	if n := DecodeInt32(DataTypeINT24, make([]int32, 2), make([]byte, 9)); n != 2 {
+		t.Errorf("DecodeInt32 decoded %d samples into 2, want 2", n)
+	}
+}
+
+func TestAudioSamples256(t *testing.T) {
+	h := NewHeader(ProtocolAudio, "Stream1")
+	h.FormatNbs, h.FormatNbc = 255, 255 // 256 samples, 256 channels
+	p := Packet{Header: h}
+	if got := p.AudioSamples(); got != 256*256 {
+		t.Errorf("AudioSamples = %d, want %d", got, 256*256)
+	}
+}
@@ -0,0 +1,201 @@
+package vban
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// MaxSamplesPerFrame is the maximum number of samples per channel in one audio packet (Spec p.9).
+const MaxSamplesPerFrame = 256
+
+// AudioStreamWriter packetizes a continuous interleaved PCM stream into VBAN audio packets.
+// Each packet carries as many whole frames as allowed by both MaxPacketDataSize and
+// MaxSamplesPerFrame (or the value set by SetSamplesPerPacket). The frame counter (NuFrame)
+// is incremented for every packet sent.
+//
+// AudioStreamWriter does not pace its output; callers are expected to write audio at
+// the stream's sample rate. It is not safe for concurrent use.
+type AudioStreamWriter struct {
+	conn   *Conn
+	addr   *net.UDPAddr
+	header Header
+	format AudioFormat
+
+	frameGroup       int    // Packets must hold a multiple of this many frames (bit-packed types)
+	samplesPerPacket int    // Frames per full packet
+	buf              []byte // Pending payload of the next packet
+	n                int    // Number of valid bytes in buf
+	scratch          []byte // Encoding buffer for WriteFloat32/WriteInt32
+}
+
+// NewAudioStreamWriter creates a writer sending the stream streamName with the given format.
+// If conn was created using Dial, addr can be nil to send to the dialed address.
+func NewAudioStreamWriter(conn *Conn, addr *net.UDPAddr, streamName string, format AudioFormat) (*AudioStreamWriter, error) {
+	if conn == nil {
+		return nil, errors.New("connection cannot be nil")
+	}
+	if err := format.validate(); err != nil {
+		return nil, fmt.Errorf("invalid audio format: %w", err)
+	}
+	w := &AudioStreamWriter{
+		conn:   conn,
+		addr:   addr,
+		header: NewHeader(ProtocolAudio, streamName),
+		format: format,
+	}
+	w.header.SetAudioFormat(format.SRIndex, format.DataType, CodecPCM)
+	w.header.FormatNbc = uint8(format.Channels - 1) // SetChannels cannot express 256
+
+	// Bit-packed frames may end in the middle of a byte; find the smallest number
+	// of frames that ends on a byte boundary.
+	w.frameGroup = 1
+	for w.frameGroup*format.FrameBits()%8 != 0 {
+		w.frameGroup++
+	}
+	maxFrames := min(MaxSamplesPerFrame, MaxPacketDataSize*8/format.FrameBits())
+	maxFrames -= maxFrames % w.frameGroup
+	if maxFrames == 0 {
+		return nil, fmt.Errorf("a single frame (%d channels of %d bits) exceeds the VBAN payload limit of %d bytes",
+			format.Channels, format.DataType.BitsPerSample(), MaxPacketDataSize)
+	}
+	w.setSamplesPerPacket(maxFrames)
+	return w, nil
+}
+
+// setSamplesPerPacket resizes the packet buffer. Pending data is kept as far as it fits.
+func (w *AudioStreamWriter) setSamplesPerPacket(frames int) {
+	w.samplesPerPacket = frames
+	size := frames * w.format.FrameBits() / 8
+	buf := make([]byte, size)
+	w.n = copy(buf, w.buf[:w.n])
+	w.buf = buf
+}
+
+// SetSamplesPerPacket sets the number of frames (samples per channel) sent in each packet.
+// It must not exceed the maximum computed for the stream format, and must be a multiple of
+// the frame group required by bit-packed data types. Smaller packets lower the latency at
+// the cost of more network overhead. Call Flush first to avoid resizing pending data.
+func (w *AudioStreamWriter) SetSamplesPerPacket(frames int) error {
+	maxFrames := min(MaxSamplesPerFrame, MaxPacketDataSize*8/w.format.FrameBits())
+	if frames < 1 || frames > maxFrames {
+		return fmt.Errorf("samples per packet must be between 1 and %d for this format, got %d", maxFrames, frames)
+	}
+	if frames%w.frameGroup != 0 {
+		return fmt.Errorf("samples per packet must be a multiple of %d for data type %d with %d channels",
+			w.frameGroup, w.format.DataType, w.format.Channels)
+	}
+	w.setSamplesPerPacket(frames)
+	return nil
+}
+
+// SamplesPerPacket returns the number of frames sent in each full packet.
+func (w *AudioStreamWriter) SamplesPerPacket() int { return w.samplesPerPacket }
+
+// Format returns the audio format of the stream.
+func (w *AudioStreamWriter) Format() AudioFormat { return w.format }
+
+// FrameCounter returns the NuFrame value that will be used for the next packet.
+func (w *AudioStreamWriter) FrameCounter() uint32 { return w.header.NuFrame }
+
+// send transmits the first frames of payload as a single packet.
+func (w *AudioStreamWriter) send(payload []byte, frames int) error {
+	w.header.FormatNbs = uint8(frames - 1) // SetSamplesPerFrame cannot express 256
+	packet, err := NewPacket(w.header, payload)
+	if err != nil {
+		return err
+	}
+	if err := w.conn.Send(packet, w.addr); err != nil {
+		return fmt.Errorf("failed to send audio packet %d: %w", w.header.NuFrame, err)
+	}
+	w.header.NuFrame++
+	return nil
+}
+
+// Write implements io.Writer. p holds raw interleaved samples encoded in the stream's
+// DataType (see EncodeFloat32/EncodeInt32). Data is sent as soon as a full packet is
+// available; the remainder is buffered until the next Write or Flush.
+// If sending fails, the affected packet is dropped and the error is returned.
+func (w *AudioStreamWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		k := copy(w.buf[w.n:], p)
+		w.n += k
+		p = p[k:]
+		written += k
+		if w.n == len(w.buf) {
+			w.n = 0
+			if err := w.send(w.buf, w.samplesPerPacket); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// checkSampleCount verifies that an interleaved sample buffer holds whole frames.
+func (w *AudioStreamWriter) checkSampleCount(nbSamples int) error {
+	group := w.format.Channels * w.frameGroup
+	if nbSamples%group != 0 {
+		return fmt.Errorf("sample count %d is not a multiple of %d (whole frames required)", nbSamples, group)
+	}
+	return nil
+}
+
+// WriteFloat32 encodes normalized interleaved samples into the stream's DataType and writes them.
+// The number of samples must be a multiple of the channel count (and of the frame group for
+// bit-packed data types).
+func (w *AudioStreamWriter) WriteFloat32(samples []float32) error {
+	if err := w.checkSampleCount(len(samples)); err != nil {
+		return err
+	}
+	chunk := w.samplesPerPacket * w.format.Channels
+	if len(w.scratch) < len(w.buf) {
+		w.scratch = make([]byte, len(w.buf))
+	}
+	for len(samples) > 0 {
+		k := min(chunk, len(samples))
+		nb := EncodeFloat32(w.format.DataType, w.scratch, samples[:k])
+		if _, err := w.Write(w.scratch[:nb]); err != nil {
+			return err
+		}
+		samples = samples[k:]
+	}
+	return nil
+}
+
+// WriteInt32 encodes MSB-aligned interleaved samples into the stream's DataType and writes them.
+// The number of samples must be a multiple of the channel count (and of the frame group for
+// bit-packed data types).
+func (w *AudioStreamWriter) WriteInt32(samples []int32) error {
+	if err := w.checkSampleCount(len(samples)); err != nil {
+		return err
+	}
+	chunk := w.samplesPerPacket * w.format.Channels
+	if len(w.scratch) < len(w.buf) {
+		w.scratch = make([]byte, len(w.buf))
+	}
+	for len(samples) > 0 {
+		k := min(chunk, len(samples))
+		nb := EncodeInt32(w.format.DataType, w.scratch, samples[:k])
+		if _, err := w.Write(w.scratch[:nb]); err != nil {
+			return err
+		}
+		samples = samples[k:]
+	}
+	return nil
+}
+
+// Flush sends all buffered whole frames as a (possibly shorter) packet.
+// Bytes not forming a complete frame group remain buffered.
+func (w *AudioStreamWriter) Flush() error {
+	frames := w.n * 8 / w.format.FrameBits()
+	frames -= frames % w.frameGroup
+	if frames == 0 {
+		return nil
+	}
+	size := frames * w.format.FrameBits() / 8
+	err := w.send(w.buf[:size], frames)
+	w.n = copy(w.buf, w.buf[size:w.n])
+	return err
+}
@@ -0,0 +1,383 @@
+package vban
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// --- Audio Files (WAV, RF64, AIFF, AIFF-C) ---
+
+// AudioFile reads uncompressed audio from a WAV (including WAVE_FORMAT_EXTENSIBLE and
+// RF64) or AIFF/AIFF-C file and returns it in the layout of the matching VBAN DataType:
+// 8-bit files are read as UINT8, 16/24/32-bit integer files as INT16/INT24/INT32, and
+// 32/64-bit float files as FLOAT32/FLOAT64. Samples with fewer valid bits than their
+// container (e.g. 20 bits in 24) are read as the container type. The sample rate must be
+// one of the rates in SRList.
+//
+// AudioFile is an io.Reader and can be copied to an AudioStreamWriter directly. It is not
+// safe for concurrent use.
+type AudioFile struct {
+	r      io.ReadSeeker
+	closer io.Closer
+	format AudioFormat
+
+	frameSize  int   // Bytes per sample frame
+	dataOffset int64 // File offset of the first frame
+	frames     int64 // Number of frames in the file
+	pos        int64 // Index of the next frame to read
+
+	bigEndian bool // Samples are stored big-endian (AIFF)
+	signed8   bool // 8-bit samples are signed (AIFF)
+}
+
+// OpenAudioFile opens the named WAV or AIFF file. Close closes the file.
+func OpenAudioFile(name string) (*AudioFile, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	af, err := NewAudioFile(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	af.closer = f
+	return af, nil
+}
+
+// NewAudioFile parses the header of a WAV or AIFF file read from r, leaving r positioned
+// at the first sample frame.
+func NewAudioFile(r io.ReadSeeker) (*AudioFile, error) {
+	var hdr [12]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, fmt.Errorf("failed to read audio file header: %w", err)
+	}
+	af := &AudioFile{r: r}
+	var (
+		rate uint32
+		err  error
+	)
+	switch {
+	case (string(hdr[0:4]) == "RIFF" || string(hdr[0:4]) == "RF64") && string(hdr[8:12]) == "WAVE":
+		rate, err = af.parseWAV()
+	case string(hdr[0:4]) == "FORM" && (string(hdr[8:12]) == "AIFF" || string(hdr[8:12]) == "AIFC"):
+		rate, err = af.parseAIFF(string(hdr[8:12]) == "AIFC")
+	default:
+		return nil, errors.New("unknown audio file format (expected WAV or AIFF)")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if af.format.SRIndex, err = FindSRIndex(rate); err != nil {
+		return nil, err
+	}
+	if err := af.format.validate(); err != nil {
+		return nil, err
+	}
+
+	// Clamp the data size to the file size, e.g. for an unfinished recording.
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if avail := (end - af.dataOffset) / int64(af.frameSize); af.frames < 0 || af.frames > avail {
+		af.frames = max(avail, 0)
+	}
+	if err := af.SeekFrame(0); err != nil {
+		return nil, err
+	}
+	return af, nil
+}
+
+// chunk reads the next chunk header. It returns io.EOF when no chunk is left.
+func (af *AudioFile) chunk(order binary.ByteOrder) (id string, size int64, err error) {
+	var hdr [8]byte
+	if _, err := io.ReadFull(af.r, hdr[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return "", 0, err
+	}
+	return string(hdr[:4]), int64(order.Uint32(hdr[4:])), nil
+}
+
+// skip moves past the rest of a chunk body of the given size, including the pad byte.
+func (af *AudioFile) skip(size int64) error {
+	_, err := af.r.Seek(size+size&1, io.SeekCurrent)
+	return err
+}
+
+// body reads a chunk body of the given size, which must be at least min bytes long.
+func (af *AudioFile) body(id string, size int64, minSize int) ([]byte, error) {
+	if size < int64(minSize) || size > 1<<16 {
+		return nil, fmt.Errorf("invalid %q chunk size: %d", id, size)
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(af.r, b); err != nil {
+		return nil, fmt.Errorf("failed to read %q chunk: %w", id, err)
+	}
+	if size&1 != 0 {
+		if _, err := af.r.Seek(1, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// parseWAV reads the chunks of a WAV or RF64 file up to the data chunk.
+func (af *AudioFile) parseWAV() (rate uint32, err error) {
+	le := binary.LittleEndian
+	var (
+		ds64Data int64 = -1
+		haveFmt  bool
+	)
+	for {
+		id, size, err := af.chunk(le)
+		if err == io.EOF {
+			return 0, errors.New("WAV file has no data chunk")
+		}
+		if err != nil {
+			return 0, err
+		}
+		switch id {
+		case "ds64":
+			b, err := af.body(id, size, 24)
+			if err != nil {
+				return 0, err
+			}
+			ds64Data = int64(le.Uint64(b[8:]))
+		case "fmt ":
+			b, err := af.body(id, size, 16)
+			if err != nil {
+				return 0, err
+			}
+			tag := le.Uint16(b[0:])
+			channels := int(le.Uint16(b[2:]))
+			rate = le.Uint32(b[4:])
+			blockAlign := int(le.Uint16(b[12:]))
+			bits := int(le.Uint16(b[14:]))
+			if tag == wavFormatExtensible {
+				if len(b) < 40 {
+					return 0, errors.New("invalid WAVE_FORMAT_EXTENSIBLE header")
+				}
+				tag = le.Uint16(b[24:]) // First field of the SubFormat GUID
+			}
+			if channels < 1 || bits == 0 || blockAlign != channels*((bits+7)/8) {
+				return 0, fmt.Errorf("invalid WAV format: %d channels, %d bits, block size %d", channels, bits, blockAlign)
+			}
+			af.format.Channels = channels
+			af.frameSize = blockAlign
+			switch {
+			case tag == wavFormatPCM && bits <= 8:
+				af.format.DataType = DataTypeUINT8
+			case tag == wavFormatPCM && bits <= 16:
+				af.format.DataType = DataTypeINT16
+			case tag == wavFormatPCM && bits <= 24:
+				af.format.DataType = DataTypeINT24
+			case tag == wavFormatPCM && bits <= 32:
+				af.format.DataType = DataTypeINT32
+			case tag == wavFormatIEEEFloat && bits == 32:
+				af.format.DataType = DataTypeFLOAT32
+			case tag == wavFormatIEEEFloat && bits == 64:
+				af.format.DataType = DataTypeFLOAT64
+			default:
+				return 0, fmt.Errorf("unsupported WAV format: tag %#04x with %d bits", tag, bits)
+			}
+			haveFmt = true
+		case "data":
+			if !haveFmt {
+				return 0, errors.New("WAV data chunk precedes the fmt chunk")
+			}
+			if size == math.MaxUint32 && ds64Data >= 0 {
+				size = ds64Data
+			}
+			if af.dataOffset, err = af.r.Seek(0, io.SeekCurrent); err != nil {
+				return 0, err
+			}
+			af.frames = size / int64(af.frameSize)
+			return rate, nil
+		default:
+			if err := af.skip(size); err != nil {
+				return 0, err
+			}
+		}
+	}
+}
+
+// parseAIFF reads the chunks of an AIFF or AIFF-C file up to the sound data chunk.
+func (af *AudioFile) parseAIFF(aifc bool) (rate uint32, err error) {
+	be := binary.BigEndian
+	var haveComm bool
+	for {
+		id, size, err := af.chunk(be)
+		if err == io.EOF {
+			return 0, errors.New("AIFF file has no sound data chunk")
+		}
+		if err != nil {
+			return 0, err
+		}
+		switch id {
+		case "COMM":
+			minSize := 18
+			if aifc {
+				minSize = 22
+			}
+			b, err := af.body(id, size, minSize)
+			if err != nil {
+				return 0, err
+			}
+			channels := int(be.Uint16(b[0:]))
+			bits := int(be.Uint16(b[6:]))
+			rate = uint32(math.Round(extendedToFloat(b[8:18])))
+			compression := "NONE"
+			if aifc {
+				compression = string(b[18:22])
+			}
+			if channels < 1 || bits < 1 || bits > 64 {
+				return 0, fmt.Errorf("invalid AIFF format: %d channels, %d bits", channels, bits)
+			}
+			af.format.Channels = channels
+			af.frames = int64(be.Uint32(b[2:]))
+			af.bigEndian = true
+			switch {
+			case (compression == "NONE" || compression == "twos") && bits <= 8:
+				af.format.DataType = DataTypeUINT8
+				af.signed8 = true
+			case (compression == "NONE" || compression == "twos") && bits <= 16:
+				af.format.DataType = DataTypeINT16
+			case (compression == "NONE" || compression == "twos") && bits <= 24:
+				af.format.DataType = DataTypeINT24
+			case (compression == "NONE" || compression == "twos") && bits <= 32:
+				af.format.DataType = DataTypeINT32
+			case compression == "sowt" && bits <= 16:
+				af.format.DataType = DataTypeINT16
+				af.bigEndian = false
+			case compression == "sowt" && bits <= 24:
+				af.format.DataType = DataTypeINT24
+				af.bigEndian = false
+			case compression == "sowt" && bits <= 32:
+				af.format.DataType = DataTypeINT32
+				af.bigEndian = false
+			case compression == "fl32" || compression == "FL32":
+				af.format.DataType = DataTypeFLOAT32
+			case compression == "fl64" || compression == "FL64":
+				af.format.DataType = DataTypeFLOAT64
+			default:
+				return 0, fmt.Errorf("unsupported AIFF format: compression %q with %d bits", compression, bits)
+			}
+			af.frameSize = channels * af.format.DataType.Size()
+			haveComm = true
+		case "SSND":
+			if !haveComm {
+				return 0, errors.New("AIFF sound data chunk precedes the COMM chunk")
+			}
+			var b [8]byte
+			if _, err := io.ReadFull(af.r, b[:]); err != nil {
+				return 0, fmt.Errorf("failed to read \"SSND\" chunk: %w", err)
+			}
+			pos, err := af.r.Seek(int64(be.Uint32(b[0:])), io.SeekCurrent) // Skip the block offset
+			if err != nil {
+				return 0, err
+			}
+			af.dataOffset = pos
+			return rate, nil
+		default:
+			if err := af.skip(size); err != nil {
+				return 0, err
+			}
+		}
+	}
+}
+
+// extendedToFloat converts an 80-bit IEEE 754 extended precision number (as used for the
+// AIFF sample rate) to a float64.
+func extendedToFloat(b []byte) float64 {
+	exp := int(binary.BigEndian.Uint16(b[0:]))
+	mant := binary.BigEndian.Uint64(b[2:])
+	sign := 1.0
+	if exp&0x8000 != 0 {
+		sign = -1
+		exp &= 0x7FFF
+	}
+	if exp == 0 && mant == 0 {
+		return 0
+	}
+	return sign * math.Ldexp(float64(mant), exp-16383-63)
+}
+
+// Format returns the format of the audio data as read by Read.
+func (af *AudioFile) Format() AudioFormat { return af.format }
+
+// Frames returns the number of sample frames in the file.
+func (af *AudioFile) Frames() int64 { return af.frames }
+
+// Position returns the index of the next frame to be read.
+func (af *AudioFile) Position() int64 { return af.pos }
+
+// SeekFrame moves the read position to the given frame, clamped to the file length.
+func (af *AudioFile) SeekFrame(frame int64) error {
+	frame = min(max(frame, 0), af.frames)
+	if _, err := af.r.Seek(af.dataOffset+frame*int64(af.frameSize), io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek audio file: %w", err)
+	}
+	af.pos = frame
+	return nil
+}
+
+// Read implements io.Reader. It reads whole frames of raw samples in the layout of the
+// Format's DataType into p, which must hold at least one frame, and returns io.EOF at the
+// end of the audio data.
+func (af *AudioFile) Read(p []byte) (int, error) {
+	frames := min(int64(len(p)/af.frameSize), af.frames-af.pos)
+	if af.pos >= af.frames {
+		return 0, io.EOF
+	}
+	if frames == 0 {
+		return 0, io.ErrShortBuffer
+	}
+	n, err := io.ReadFull(af.r, p[:frames*int64(af.frameSize)])
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		// The file is shorter than its header claims.
+		af.frames = af.pos + int64(n/af.frameSize)
+		err = nil
+	}
+	n -= n % af.frameSize
+	af.pos += int64(n / af.frameSize)
+	af.convert(p[:n])
+	if n == 0 && err == nil {
+		return 0, io.EOF
+	}
+	return n, err
+}
+
+// convert rewrites file samples in place into the VBAN layout.
+func (af *AudioFile) convert(p []byte) {
+	if af.signed8 {
+		for i := range p {
+			p[i] ^= 0x80
+		}
+		return
+	}
+	if !af.bigEndian {
+		return
+	}
+	size := af.format.DataType.Size()
+	for i := 0; i+size <= len(p); i += size {
+		s := p[i : i+size]
+		for a, b := 0, size-1; a < b; a, b = a+1, b-1 {
+			s[a], s[b] = s[b], s[a]
+		}
+	}
+}
+
+// Close closes the underlying file if it was opened by OpenAudioFile.
+func (af *AudioFile) Close() error {
+	if af.closer == nil {
+		return nil
+	}
+	return af.closer.Close()
+}
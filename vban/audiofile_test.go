@@ -0,0 +1,112 @@
+package vban
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+// extended48000 is 48000 as an 80-bit IEEE 754 extended precision number.
+var extended48000 = []byte{0x40, 0x0E, 0xBB, 0x80, 0, 0, 0, 0, 0, 0}
+
+func TestExtendedToFloat(t *testing.T) {
+	tests := []struct {
+		b    []byte
+		want float64
+	}{
+		{[]byte{0x40, 0x0E, 0xAC, 0x44, 0, 0, 0, 0, 0, 0}, 44100},
+		{extended48000, 48000},
+		{[]byte{0x40, 0x0B, 0xFA, 0x00, 0, 0, 0, 0, 0, 0}, 8000},
+		{[]byte{0x40, 0x0D, 0xAC, 0x44, 0, 0, 0, 0, 0, 0}, 22050},
+		{[]byte{0x40, 0x10, 0xBB, 0x80, 0, 0, 0, 0, 0, 0}, 192000},
+		{[]byte{0x3F, 0xFF, 0x80, 0, 0, 0, 0, 0, 0, 0}, 1},
+		{[]byte{0xBF, 0xFF, 0x80, 0, 0, 0, 0, 0, 0, 0}, -1},
+		{make([]byte, 10), 0},
+	}
+	for _, tt := range tests {
+		if got := extendedToFloat(tt.b); got != tt.want {
+			t.Errorf("extendedToFloat(% X) = %v, want %v", tt.b, got, tt.want)
+		}
+	}
+}
+
+// aiffFile builds an AIFF (or AIFF-C, if compression is not empty) file with a 48 kHz
+// sample rate. An odd-sized chunk precedes the COMM chunk to exercise chunk padding.
+func aiffFile(compression string, channels, bits int, data []byte) []byte {
+	be := binary.BigEndian
+	comm := be.AppendUint16(nil, uint16(channels))
+	comm = be.AppendUint32(comm, uint32(len(data)/channels/((bits+7)/8)))
+	comm = be.AppendUint16(comm, uint16(bits))
+	comm = append(comm, extended48000...)
+	form := "AIFF"
+	if compression != "" {
+		form = "AIFC"
+		comm = append(comm, compression...)
+		comm = append(comm, 0, 0) // Empty compression name with pad byte
+	}
+
+	var body []byte
+	chunk := func(id string, b []byte) {
+		body = append(body, id...)
+		body = be.AppendUint32(body, uint32(len(b)))
+		body = append(body, b...)
+		if len(b)%2 != 0 {
+			body = append(body, 0)
+		}
+	}
+	chunk("ANNO", []byte("odd"))
+	chunk("COMM", comm)
+	chunk("SSND", append(make([]byte, 8), data...)) // Zero offset and block size
+
+	file := append([]byte("FORM"), be.AppendUint32(nil, uint32(4+len(body)))...)
+	file = append(file, form...)
+	return append(file, body...)
+}
+
+// readAudioFile parses a file and reads all of its samples.
+func readAudioFile(t *testing.T, file []byte) (*AudioFile, []byte) {
+	t.Helper()
+	af, err := NewAudioFile(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("NewAudioFile: %v", err)
+	}
+	data, err := io.ReadAll(af)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	return af, data
+}
+
+func TestAIFF8BitSigned(t *testing.T) {
+	af, data := readAudioFile(t, aiffFile("", 1, 8, []byte{0x00, 0x7F, 0x80, 0xFF, 0x01}))
+	want := AudioFormat{SRIndex: 3, DataType: DataTypeUINT8, Channels: 1}
+	if af.Format() != want || af.Frames() != 5 {
+		t.Fatalf("got %+v with %d frames, want %+v with 5 frames", af.Format(), af.Frames(), want)
+	}
+	// Signed 8-bit samples become offset binary: 0 is 0x80.
+	if wantData := []byte{0x80, 0xFF, 0x00, 0x7F, 0x81}; !bytes.Equal(data, wantData) {
+		t.Errorf("got % X, want % X", data, wantData)
+	}
+}
+
+func TestAIFF16BitBigEndian(t *testing.T) {
+	af, data := readAudioFile(t, aiffFile("", 2, 16, []byte{0x12, 0x34, 0xFF, 0xFE, 0x80, 0x00, 0x7F, 0xFF}))
+	if f := af.Format(); f.DataType != DataTypeINT16 || f.Channels != 2 || af.Frames() != 2 {
+		t.Fatalf("got %+v with %d frames", f, af.Frames())
+	}
+	if want := []byte{0x34, 0x12, 0xFE, 0xFF, 0x00, 0x80, 0xFF, 0x7F}; !bytes.Equal(data, want) {
+		t.Errorf("got % X, want % X", data, want)
+	}
+}
+
+func TestAIFC24BitLittleEndian(t *testing.T) {
+	samples := []byte{0x56, 0x34, 0x12, 0xFE, 0xFF, 0xFF, 0x01, 0x00, 0x80}
+	af, data := readAudioFile(t, aiffFile("sowt", 1, 24, samples))
+	if f := af.Format(); f.DataType != DataTypeINT24 || af.Frames() != 3 {
+		t.Fatalf("got %+v with %d frames", f, af.Frames())
+	}
+	if !bytes.Equal(data, samples) {
+		t.Errorf("got % X, want % X", data, samples)
+	}
+}
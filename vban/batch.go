@@ -0,0 +1,119 @@
+package vban
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// --- Batched I/O ---
+
+// recvBatch holds the buffers reused by ReceiveBatch.
+type recvBatch struct {
+	bufs [][]byte         // One datagram buffer of MaxVBANPacketSize+1 bytes per message
+	lens []int            // Number of bytes received in each buffer
+	from []netip.AddrPort // Sender of each datagram
+}
+
+// SendBatch marshals packets and sends them to addr using as few system calls as the
+// platform allows (sendmmsg on Linux, one write per packet elsewhere).
+// If the Conn was created using Dial, addr can be nil to send to the dialed address.
+// It returns the number of packets sent; on error, packets[n] is the first packet not sent.
+func (c *Conn) SendBatch(packets []*Packet, addr *net.UDPAddr) (int, error) {
+	udpConn, err := c.conn()
+	if err != nil {
+		return 0, err
+	}
+	if addr == nil && udpConn.RemoteAddr() == nil {
+		// Not a dialed connection, and no destination address provided
+		return 0, errors.New("destination address (addr) must be provided for non-dialed connections")
+	}
+
+	// Marshal all packets into a single buffer
+	size := 0
+	for i, packet := range packets {
+		if packet == nil {
+			return 0, fmt.Errorf("cannot send a nil packet (index %d)", i)
+		}
+		size += HeaderSize + len(packet.Data)
+	}
+	buf := make([]byte, 0, size)
+	bufs := make([][]byte, len(packets))
+	for i, packet := range packets {
+		start := len(buf)
+		var err error
+		if buf, err = packet.AppendBinary(buf); err != nil {
+			return 0, fmt.Errorf("failed to marshal packet %d for sending: %w", i, err)
+		}
+		bufs[i] = buf[start:len(buf):len(buf)]
+	}
+
+	n, err := c.writeBatch(udpConn, bufs, addr)
+	if err != nil {
+		return n, fmt.Errorf("UDP batch write error: %w", closedError(err))
+	}
+	return n, nil
+}
+
+// ReceiveBatch blocks until at least one VBAN packet is available and then reads up to
+// len(packets) datagrams using as few system calls as the platform allows (recvmmsg on
+// Linux, one read per call elsewhere). Packets are decoded into packets[:n], reusing the
+// capacity of each Data as Packet.UnmarshalBinary does. If addrs is not nil, it must be at
+// least as long as packets and receives the sender of each packet.
+// Datagrams that are not valid VBAN packets, or that fail validation in strict mode, are
+// dropped.
+// ReceiveBatch must not be called concurrently with other receives.
+func (c *Conn) ReceiveBatch(packets []Packet, addrs []netip.AddrPort) (int, error) {
+	udpConn, err := c.conn()
+	if err != nil {
+		return 0, err
+	}
+	if addrs != nil && len(addrs) < len(packets) {
+		return 0, fmt.Errorf("addrs too short: got %d elements, need %d", len(addrs), len(packets))
+	}
+	if len(packets) == 0 {
+		return 0, nil
+	}
+
+	bufs, lens, from := c.recvBuffers(len(packets))
+	for {
+		m, err := c.readBatch(udpConn, bufs, lens, from)
+		if err != nil {
+			return 0, readError(err)
+		}
+		n := 0
+		for i := range m {
+			if lens[i] < HeaderSize || lens[i] > MaxVBANPacketSize {
+				continue // Not a valid VBAN packet
+			}
+			addr := netip.AddrPortFrom(from[i].Addr().Unmap(), from[i].Port())
+			view, err := c.decode(bufs[i][:lens[i]], addr)
+			if err != nil {
+				continue // Not a valid VBAN packet, or rejected in strict mode
+			}
+			packets[n].Header = view.Header
+			packets[n].Data = append(packets[n].Data[:0], view.Data...)
+			if addrs != nil {
+				addrs[n] = addr
+			}
+			n++
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// recvBuffers returns the receive buffers for n datagrams, growing them if needed.
+func (c *Conn) recvBuffers(n int) ([][]byte, []int, []netip.AddrPort) {
+	rb := &c.recv
+	for len(rb.bufs) < n {
+		rb.bufs = append(rb.bufs, make([]byte, MaxVBANPacketSize+1))
+	}
+	if len(rb.lens) < n {
+		rb.lens = make([]int, n)
+		rb.from = make([]netip.AddrPort, n)
+	}
+	return rb.bufs[:n], rb.lens[:n], rb.from[:n]
+}
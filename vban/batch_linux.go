@@ -0,0 +1,76 @@
+//go:build linux
+
+package vban
+
+import (
+	"net"
+	"net/netip"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// batchState holds the message headers reused by readBatch.
+type batchState struct {
+	msgs []ipv4.Message
+}
+
+// batchConn is implemented by both ipv4.PacketConn and ipv6.PacketConn, which share the
+// same message type.
+type batchConn interface {
+	ReadBatch(ms []ipv4.Message, flags int) (int, error)
+	WriteBatch(ms []ipv4.Message, flags int) (int, error)
+}
+
+// newBatchConn wraps udpConn for recvmmsg/sendmmsg according to its address family.
+func newBatchConn(udpConn *net.UDPConn) batchConn {
+	if la, ok := udpConn.LocalAddr().(*net.UDPAddr); ok && la.IP.To4() == nil {
+		return ipv6.NewPacketConn(udpConn)
+	}
+	return ipv4.NewPacketConn(udpConn)
+}
+
+// writeBatch sends bufs to addr (nil for a dialed Conn) with sendmmsg.
+func (c *Conn) writeBatch(udpConn *net.UDPConn, bufs [][]byte, addr *net.UDPAddr) (int, error) {
+	msgs := make([]ipv4.Message, len(bufs))
+	for i := range msgs {
+		msgs[i].Buffers = bufs[i : i+1]
+		if addr != nil {
+			msgs[i].Addr = addr
+		}
+	}
+	pc := newBatchConn(udpConn)
+	sent := 0
+	for sent < len(msgs) {
+		n, err := pc.WriteBatch(msgs[sent:], 0)
+		sent += n
+		if err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// readBatch blocks until at least one datagram is available and receives up to len(bufs)
+// datagrams with recvmmsg, storing their lengths and senders in lens and from.
+func (c *Conn) readBatch(udpConn *net.UDPConn, bufs [][]byte, lens []int, from []netip.AddrPort) (int, error) {
+	if len(c.batch.msgs) < len(bufs) {
+		c.batch.msgs = make([]ipv4.Message, len(bufs))
+	}
+	msgs := c.batch.msgs[:len(bufs)]
+	for i := range msgs {
+		msgs[i].Buffers = bufs[i : i+1]
+	}
+	n, err := newBatchConn(udpConn).ReadBatch(msgs, 0)
+	if err != nil {
+		return 0, err
+	}
+	for i := range n {
+		lens[i] = msgs[i].N
+		from[i] = netip.AddrPort{}
+		if addr, ok := msgs[i].Addr.(*net.UDPAddr); ok {
+			from[i] = addr.AddrPort()
+		}
+	}
+	return n, nil
+}
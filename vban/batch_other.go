@@ -0,0 +1,37 @@
+//go:build !linux
+
+package vban
+
+import (
+	"net"
+	"net/netip"
+)
+
+// batchState is empty on platforms without recvmmsg/sendmmsg.
+type batchState struct{}
+
+// writeBatch sends bufs to addr (nil for a dialed Conn), one write per datagram.
+func (c *Conn) writeBatch(udpConn *net.UDPConn, bufs [][]byte, addr *net.UDPAddr) (int, error) {
+	for i, buf := range bufs {
+		var err error
+		if addr != nil {
+			_, err = udpConn.WriteToUDP(buf, addr)
+		} else {
+			_, err = udpConn.Write(buf)
+		}
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(bufs), nil
+}
+
+// readBatch blocks until a datagram is available and receives it into bufs[0].
+func (c *Conn) readBatch(udpConn *net.UDPConn, bufs [][]byte, lens []int, from []netip.AddrPort) (int, error) {
+	n, addr, err := udpConn.ReadFromUDPAddrPort(bufs[0])
+	if err != nil {
+		return 0, err
+	}
+	lens[0], from[0] = n, addr
+	return 1, nil
+}
@@ -0,0 +1,97 @@
+package vban
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+)
+
+// --- Broadcast ---
+
+// ListenBroadcast is like Listen but also enables sending to broadcast addresses, so that
+// the Conn can both send to and receive from x.x.x.255 or 255.255.255.255. Broadcast
+// packets are only received if localAddr is nil or has an unspecified IP address.
+func ListenBroadcast(localAddr *net.UDPAddr, opts ...Option) (*Conn, error) {
+	return Listen(localAddr, append(slices.Clip(opts), WithBroadcast())...)
+}
+
+// DialBroadcast is like Dial for a broadcast destination such as 192.168.1.255:6980 or
+// 255.255.255.255:6980 (see BroadcastAddrs). Broadcast is enabled before the socket is
+// connected, which most systems require for broadcast destinations. If remoteAddr.Port
+// is 0, DefaultPort is used.
+func DialBroadcast(localAddr, remoteAddr *net.UDPAddr, opts ...Option) (*Conn, error) {
+	if remoteAddr == nil {
+		return nil, errors.New("remote address cannot be nil for Dial")
+	}
+	if remoteAddr.IP.To4() == nil {
+		return nil, fmt.Errorf("%s is not an IPv4 address; IPv6 has no broadcast", remoteAddr)
+	}
+	addr := *remoteAddr
+	if addr.Port == 0 {
+		addr.Port = DefaultPort
+	}
+	return Dial(localAddr, &addr, append(slices.Clip(opts), WithBroadcast())...)
+}
+
+// SetBroadcast enables or disables sending to broadcast addresses (SO_BROADCAST). Without
+// it, sending to a broadcast address fails with a permission error on most systems.
+func (c *Conn) SetBroadcast(on bool) error {
+	udpConn, err := c.conn()
+	if err != nil {
+		return err
+	}
+	raw, err := udpConn.SyscallConn()
+	if err == nil {
+		err = setBroadcast(raw, on)
+	}
+	if err != nil {
+		if on {
+			return fmt.Errorf("failed to enable broadcast: %w", err)
+		}
+		return fmt.Errorf("failed to disable broadcast: %w", err)
+	}
+	return nil
+}
+
+// BroadcastAddrs returns the directed broadcast addresses (e.g. 192.168.1.255) of the IPv4
+// networks on all local interfaces that are up and support broadcast, with DefaultPort.
+// Loopback and point-to-point interfaces are skipped. The addresses can be used with
+// DialBroadcast, Discover or a Conn with broadcast enabled.
+func BroadcastAddrs() ([]*net.UDPAddr, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network interfaces: %w", err)
+	}
+	var addrs []*net.UDPAddr
+	seen := make(map[string]bool)
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagBroadcast == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		ifAddrs, err := iface.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list addresses of %s: %w", iface.Name, err)
+		}
+		for _, a := range ifAddrs {
+			ipNet, ok := a.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			ip := ipNet.IP.To4()
+			if ip == nil || len(ipNet.Mask) != net.IPv4len {
+				continue
+			}
+			bcast := make(net.IP, net.IPv4len)
+			for i := range bcast {
+				bcast[i] = ip[i] | ^ipNet.Mask[i]
+			}
+			if bcast.Equal(ip) || seen[bcast.String()] {
+				continue // /32 host address, or a network already listed
+			}
+			seen[bcast.String()] = true
+			addrs = append(addrs, &net.UDPAddr{IP: bcast, Port: DefaultPort})
+		}
+	}
+	return addrs, nil
+}
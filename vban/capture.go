@@ -0,0 +1,224 @@
+package vban
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// --- Packet Capture ---
+
+// CaptureRecord is one captured VBAN packet.
+type CaptureRecord struct {
+	Time time.Time      // Arrival time
+	Src  netip.AddrPort // Sender address
+	Dst  netip.AddrPort // Receiving (local) address; may be invalid if unknown
+	Data []byte         // Raw packet as produced by Packet.MarshalBinary (header + payload)
+}
+
+// CaptureFormat selects the file format written by NewCaptureWriter.
+type CaptureFormat int
+
+const (
+	CaptureNative CaptureFormat = iota // Compact native format (see NewCaptureWriter)
+	CapturePcap                        // libpcap format with nanosecond timestamps (LINKTYPE_RAW)
+	CapturePcapNG                      // pcapng format with nanosecond timestamps (LINKTYPE_RAW)
+)
+
+// CaptureWriter writes capture records to a file.
+type CaptureWriter interface {
+	WriteRecord(rec CaptureRecord) error
+}
+
+// CaptureReader reads capture records from a file. ReadRecord returns io.EOF at the end of
+// the file. The Data of a returned record is only valid until the next call.
+type CaptureReader interface {
+	ReadRecord() (CaptureRecord, error)
+}
+
+// NewCaptureWriter writes the file header of the given format to w and returns a writer
+// for the records. Records are written to w directly; wrap w in a bufio.Writer (and flush
+// it when done) for efficiency.
+//
+// The native format starts with the 8-byte magic "VBANCAP1", followed by records of
+// (Little Endian): arrival time in nanoseconds since the Unix epoch (int64), source
+// address length (uint8, 4 or 16), source IP, source port (uint16), destination address
+// length (uint8, 0, 4 or 16), destination IP, destination port (uint16), packet length
+// (uint16) and the packet bytes.
+//
+// The pcap and pcapng formats store each packet as a synthesized IPv4 or IPv6 UDP
+// datagram, so captures can be inspected with Wireshark.
+func NewCaptureWriter(w io.Writer, format CaptureFormat) (CaptureWriter, error) {
+	switch format {
+	case CaptureNative:
+		return newNativeWriter(w)
+	case CapturePcap:
+		return newPcapWriter(w)
+	case CapturePcapNG:
+		return newPcapNGWriter(w)
+	default:
+		return nil, fmt.Errorf("unknown capture format: %d", format)
+	}
+}
+
+// NewCaptureReader detects the format of a capture file (native, pcap or pcapng) and
+// returns a reader for its records. pcap and pcapng files may use the raw IP, Ethernet,
+// Linux cooked (SLL) or BSD loopback link types, as written by tcpdump or Wireshark;
+// packets that are not UDP datagrams carrying VBAN packets are skipped.
+func NewCaptureReader(r io.Reader) (CaptureReader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(8)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read capture file header: %w", err)
+	}
+	switch {
+	case bytes.Equal(magic, []byte(nativeCaptureMagic)):
+		return newNativeReader(br)
+	case bytes.Equal(magic[:4], []byte{0x0A, 0x0D, 0x0D, 0x0A}):
+		return newPcapNGReader(br)
+	default:
+		return newPcapReader(br)
+	}
+}
+
+// Recorder captures every packet passed to it, then forwards the packet to the next
+// Handler (if any). Use it as a Mux's handler chain entry point or call Serve to record
+// everything a Conn receives. Recorder is safe for concurrent use.
+type Recorder struct {
+	conn  *Conn
+	w     CaptureWriter
+	next  Handler
+	local netip.AddrPort
+
+	mu  sync.Mutex
+	buf []byte
+	err error
+}
+
+// NewRecorder creates a Recorder writing to w. conn provides the local address recorded
+// as the destination and is read by Serve; it may be nil if packets are only delivered
+// through HandlePacket. next may be nil.
+func NewRecorder(w CaptureWriter, conn *Conn, next Handler) (*Recorder, error) {
+	if w == nil {
+		return nil, errors.New("capture writer cannot be nil")
+	}
+	r := &Recorder{conn: conn, w: w, next: next}
+	if conn != nil {
+		if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+			ap := addr.AddrPort()
+			r.local = netip.AddrPortFrom(ap.Addr().Unmap(), ap.Port())
+		}
+	}
+	return r, nil
+}
+
+// HandlePacket records the packet with the current time and forwards it. After the first
+// write error, recording stops (see Err) but packets are still forwarded.
+func (r *Recorder) HandlePacket(p *Packet, addr *net.UDPAddr) {
+	if p == nil {
+		return
+	}
+	now := time.Now()
+	r.mu.Lock()
+	if r.err == nil {
+		var src netip.AddrPort
+		if addr != nil {
+			ap := addr.AddrPort()
+			src = netip.AddrPortFrom(ap.Addr().Unmap(), ap.Port())
+		}
+		var err error
+		if r.buf, err = p.AppendBinary(r.buf[:0]); err == nil {
+			err = r.w.WriteRecord(CaptureRecord{Time: now, Src: src, Dst: r.local, Data: r.buf})
+		}
+		r.err = err
+	}
+	r.mu.Unlock()
+	if r.next != nil {
+		r.next.HandlePacket(p, addr)
+	}
+}
+
+// Err returns the error that stopped recording, or nil.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Serve reads packets from the Recorder's Conn until the connection is closed (returning
+// nil) or fails. Malformed packets are skipped.
+func (r *Recorder) Serve() error {
+	if r.conn == nil {
+		return errors.New("recorder has no connection")
+	}
+	return serve(r.conn, r)
+}
+
+// ReplayOptions configures Replay.
+type ReplayOptions struct {
+	Speed float64 // Playback speed factor (1 if zero); negative values send as fast as possible
+
+	// OnRecord, if set, is called before each record is sent. Returning false skips it.
+	OnRecord func(rec *CaptureRecord) bool
+}
+
+// Replay reads all records from r and sends their packets through conn to dst (nil for a
+// dialed Conn), reproducing the original inter-packet timing. Records that are not valid
+// VBAN packets are skipped. It returns nil at the end of the capture, or ctx.Err() if ctx
+// is canceled.
+func Replay(ctx context.Context, r CaptureReader, conn *Conn, dst *net.UDPAddr, opts ReplayOptions) error {
+	if conn == nil {
+		return errors.New("connection cannot be nil")
+	}
+	speed := opts.Speed
+	if speed == 0 {
+		speed = 1
+	}
+	var (
+		packet  Packet
+		first   time.Time
+		started time.Time
+	)
+	for {
+		rec, err := r.ReadRecord()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read capture record: %w", err)
+		}
+		if opts.OnRecord != nil && !opts.OnRecord(&rec) {
+			continue
+		}
+		if err := packet.UnmarshalBinary(rec.Data); err != nil {
+			continue
+		}
+		if started.IsZero() {
+			first, started = rec.Time, time.Now()
+		} else if speed > 0 {
+			due := started.Add(time.Duration(float64(rec.Time.Sub(first)) / speed))
+			if wait := time.Until(due); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return ctx.Err()
+				case <-timer.C:
+				}
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := conn.Send(&packet, dst); err != nil {
+			return fmt.Errorf("failed to replay packet: %w", err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package vban
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/netip"
+	"time"
+)
+
+// nativeCaptureMagic starts a capture file in the native format.
+const nativeCaptureMagic = "VBANCAP1"
+
+// nativeWriter writes the native capture format.
+type nativeWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+func newNativeWriter(w io.Writer) (*nativeWriter, error) {
+	if _, err := io.WriteString(w, nativeCaptureMagic); err != nil {
+		return nil, fmt.Errorf("failed to write capture file header: %w", err)
+	}
+	return &nativeWriter{w: w}, nil
+}
+
+func (nw *nativeWriter) WriteRecord(rec CaptureRecord) error {
+	if len(rec.Data) > MaxVBANPacketSize {
+		return fmt.Errorf("%w: capture record of %d bytes", ErrOversized, len(rec.Data))
+	}
+	if !rec.Src.IsValid() {
+		return fmt.Errorf("capture record has no source address")
+	}
+	b := binary.LittleEndian.AppendUint64(nw.buf[:0], uint64(rec.Time.UnixNano()))
+	b = appendNativeAddr(b, rec.Src)
+	b = appendNativeAddr(b, rec.Dst)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(rec.Data)))
+	b = append(b, rec.Data...)
+	nw.buf = b
+	if _, err := nw.w.Write(b); err != nil {
+		return fmt.Errorf("failed to write capture record: %w", err)
+	}
+	return nil
+}
+
+// appendNativeAddr appends an address as length, IP and port. An invalid address is
+// stored with a zero length.
+func appendNativeAddr(b []byte, ap netip.AddrPort) []byte {
+	if !ap.IsValid() {
+		return append(b, 0, 0, 0)
+	}
+	ip := ap.Addr().Unmap()
+	b = append(b, uint8(ip.BitLen()/8))
+	b = append(b, ip.AsSlice()...)
+	return binary.LittleEndian.AppendUint16(b, ap.Port())
+}
+
+// nativeReader reads the native capture format.
+type nativeReader struct {
+	r    io.Reader
+	data []byte
+}
+
+func newNativeReader(r io.Reader) (*nativeReader, error) {
+	magic := make([]byte, len(nativeCaptureMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, fmt.Errorf("failed to read capture file header: %w", err)
+	}
+	if string(magic) != nativeCaptureMagic {
+		return nil, fmt.Errorf("not a native capture file")
+	}
+	return &nativeReader{r: r, data: make([]byte, MaxVBANPacketSize)}, nil
+}
+
+func (nr *nativeReader) ReadRecord() (CaptureRecord, error) {
+	var rec CaptureRecord
+	var ts [8]byte
+	if _, err := io.ReadFull(nr.r, ts[:]); err != nil {
+		return rec, err // io.EOF at a record boundary
+	}
+	rec.Time = time.Unix(0, int64(binary.LittleEndian.Uint64(ts[:])))
+	var err error
+	if rec.Src, err = nr.readAddr(); err != nil {
+		return rec, err
+	}
+	if rec.Dst, err = nr.readAddr(); err != nil {
+		return rec, err
+	}
+	var size [2]byte
+	if _, err := io.ReadFull(nr.r, size[:]); err != nil {
+		return rec, truncated(err)
+	}
+	n := int(binary.LittleEndian.Uint16(size[:]))
+	if n > len(nr.data) {
+		return rec, fmt.Errorf("%w: capture record of %d bytes", ErrOversized, n)
+	}
+	if _, err := io.ReadFull(nr.r, nr.data[:n]); err != nil {
+		return rec, truncated(err)
+	}
+	rec.Data = nr.data[:n]
+	return rec, nil
+}
+
+// readAddr reads an address written by appendNativeAddr.
+func (nr *nativeReader) readAddr() (netip.AddrPort, error) {
+	var buf [19]byte
+	if _, err := io.ReadFull(nr.r, buf[:1]); err != nil {
+		return netip.AddrPort{}, truncated(err)
+	}
+	size := int(buf[0])
+	if size != 0 && size != 4 && size != 16 {
+		return netip.AddrPort{}, fmt.Errorf("invalid address length in capture record: %d", size)
+	}
+	if _, err := io.ReadFull(nr.r, buf[1:size+3]); err != nil {
+		return netip.AddrPort{}, truncated(err)
+	}
+	if size == 0 {
+		return netip.AddrPort{}, nil
+	}
+	ip, _ := netip.AddrFromSlice(buf[1 : size+1])
+	return netip.AddrPortFrom(ip, binary.LittleEndian.Uint16(buf[size+1:])), nil
+}
+
+// truncated converts an io.EOF in the middle of a record into io.ErrUnexpectedEOF.
+func truncated(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,474 @@
+package vban
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/netip"
+	"time"
+)
+
+// --- pcap / pcapng ---
+
+// Link-layer header types (https://www.tcpdump.org/linktypes.html).
+const (
+	linkTypeNull     = 0   // BSD loopback: 4-byte address family in host byte order
+	linkTypeEthernet = 1   // Ethernet II
+	linkTypeRaw      = 101 // Raw IPv4 or IPv6
+	linkTypeLinuxSLL = 113 // Linux cooked capture v1
+)
+
+const (
+	pcapMagicMicro = 0xA1B2C3D4 // pcap with microsecond timestamps
+	pcapMagicNano  = 0xA1B23C4D // pcap with nanosecond timestamps
+	pcapSnapLen    = 65535
+
+	pcapngSHB = 0x0A0D0D0A // Section Header Block
+	pcapngIDB = 0x00000001 // Interface Description Block
+	pcapngSPB = 0x00000003 // Simple Packet Block
+	pcapngEPB = 0x00000006 // Enhanced Packet Block
+
+	pcapngByteOrderMagic = 0x1A2B3C4D
+	pcapngOptTSResol     = 9 // if_tsresol option of the IDB
+	pcapngMaxBlock       = 1 << 24
+
+	ipProtoUDP = 17
+)
+
+// appendDatagram appends rec as an IPv4 or IPv6 UDP datagram. A missing or mismatching
+// destination address is replaced by the unspecified address of the source's family.
+func appendDatagram(b []byte, rec *CaptureRecord) ([]byte, error) {
+	if len(rec.Data) > MaxVBANPacketSize {
+		return b, fmt.Errorf("%w: capture record of %d bytes", ErrOversized, len(rec.Data))
+	}
+	if !rec.Src.IsValid() {
+		return b, fmt.Errorf("capture record has no source address")
+	}
+	src := rec.Src.Addr().Unmap()
+	dst := rec.Dst.Addr().Unmap()
+	if dst.BitLen() != src.BitLen() {
+		dst = netip.IPv4Unspecified()
+		if src.Is6() {
+			dst = netip.IPv6Unspecified()
+		}
+	}
+	udpLen := 8 + len(rec.Data)
+	if src.Is4() {
+		start := len(b)
+		b = append(b, 0x45, 0) // Version 4, 20-byte header
+		b = binary.BigEndian.AppendUint16(b, uint16(20+udpLen))
+		b = append(b, 0, 0, 0x40, 0, 64, ipProtoUDP, 0, 0) // ID, DF, TTL, protocol, checksum
+		b = append(b, src.AsSlice()...)
+		b = append(b, dst.AsSlice()...)
+		binary.BigEndian.PutUint16(b[start+10:], ^checksum(0, b[start:]))
+	} else {
+		b = append(b, 0x60, 0, 0, 0) // Version 6
+		b = binary.BigEndian.AppendUint16(b, uint16(udpLen))
+		b = append(b, ipProtoUDP, 64) // Next header, hop limit
+		b = append(b, src.AsSlice()...)
+		b = append(b, dst.AsSlice()...)
+	}
+	start := len(b)
+	b = binary.BigEndian.AppendUint16(b, rec.Src.Port())
+	b = binary.BigEndian.AppendUint16(b, rec.Dst.Port())
+	b = binary.BigEndian.AppendUint16(b, uint16(udpLen))
+	b = append(b, 0, 0)
+	b = append(b, rec.Data...)
+
+	// UDP checksum over the pseudo-header and the datagram.
+	sum := uint32(checksum(0, src.AsSlice())) + uint32(checksum(0, dst.AsSlice()))
+	sum += ipProtoUDP + uint32(udpLen)
+	cs := ^checksum(sum, b[start:])
+	if cs == 0 {
+		cs = 0xFFFF
+	}
+	binary.BigEndian.PutUint16(b[start+6:], cs)
+	return b, nil
+}
+
+// checksum adds data to the ones' complement sum and returns the folded result.
+func checksum(sum uint32, data []byte) uint16 {
+	for len(data) >= 2 {
+		sum += uint32(data[0])<<8 | uint32(data[1])
+		data = data[2:]
+	}
+	if len(data) == 1 {
+		sum += uint32(data[0]) << 8
+	}
+	for sum > 0xFFFF {
+		sum = sum>>16 + sum&0xFFFF
+	}
+	return uint16(sum)
+}
+
+// parseDatagram extracts a VBAN packet sent over UDP from a link-layer frame. ok is false
+// for anything else, including IP fragments.
+func parseDatagram(linkType uint32, frame []byte, order binary.ByteOrder, rec *CaptureRecord) (ok bool) {
+	switch linkType {
+	case linkTypeRaw:
+	case linkTypeEthernet:
+		if len(frame) < 14 {
+			return false
+		}
+		etherType := binary.BigEndian.Uint16(frame[12:])
+		frame = frame[14:]
+		for etherType == 0x8100 || etherType == 0x88A8 { // VLAN tags
+			if len(frame) < 4 {
+				return false
+			}
+			etherType = binary.BigEndian.Uint16(frame[2:])
+			frame = frame[4:]
+		}
+		if etherType != 0x0800 && etherType != 0x86DD {
+			return false
+		}
+	case linkTypeLinuxSLL:
+		if len(frame) < 16 {
+			return false
+		}
+		if proto := binary.BigEndian.Uint16(frame[14:]); proto != 0x0800 && proto != 0x86DD {
+			return false
+		}
+		frame = frame[16:]
+	case linkTypeNull:
+		if len(frame) < 4 {
+			return false
+		}
+		// The address family is in the byte order of the capturing host.
+		if family := order.Uint32(frame); family != 2 && family != 24 && family != 28 && family != 30 {
+			return false
+		}
+		frame = frame[4:]
+	default:
+		return false
+	}
+
+	var src, dst netip.Addr
+	if len(frame) < 1 {
+		return false
+	}
+	switch frame[0] >> 4 {
+	case 4:
+		ihl := int(frame[0]&0x0F) * 4
+		if ihl < 20 || len(frame) < ihl || frame[9] != ipProtoUDP {
+			return false
+		}
+		if binary.BigEndian.Uint16(frame[6:])&0x3FFF != 0 { // More fragments or fragment offset
+			return false
+		}
+		if total := int(binary.BigEndian.Uint16(frame[2:])); total >= ihl && total < len(frame) {
+			frame = frame[:total] // Strip Ethernet padding
+		}
+		src = netip.AddrFrom4([4]byte(frame[12:16]))
+		dst = netip.AddrFrom4([4]byte(frame[16:20]))
+		frame = frame[ihl:]
+	case 6:
+		if len(frame) < 40 || frame[6] != ipProtoUDP {
+			return false
+		}
+		src = netip.AddrFrom16([16]byte(frame[8:24]))
+		dst = netip.AddrFrom16([16]byte(frame[24:40]))
+		frame = frame[40:]
+	default:
+		return false
+	}
+	if len(frame) < 8 {
+		return false
+	}
+	udpLen := int(binary.BigEndian.Uint16(frame[4:]))
+	if udpLen < 8 || udpLen > len(frame) {
+		return false
+	}
+	data := frame[8:udpLen]
+	if len(data) < HeaderSize || byteOrder.Uint32(data) != HeaderMagic {
+		return false
+	}
+	rec.Src = netip.AddrPortFrom(src, binary.BigEndian.Uint16(frame[0:]))
+	rec.Dst = netip.AddrPortFrom(dst, binary.BigEndian.Uint16(frame[2:]))
+	rec.Data = data
+	return true
+}
+
+// pcapWriter writes libpcap files with nanosecond timestamps.
+type pcapWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+func newPcapWriter(w io.Writer) (*pcapWriter, error) {
+	b := binary.LittleEndian.AppendUint32(nil, pcapMagicNano)
+	b = binary.LittleEndian.AppendUint16(b, 2) // Version 2.4
+	b = binary.LittleEndian.AppendUint16(b, 4)
+	b = binary.LittleEndian.AppendUint32(b, 0) // Reserved (formerly thiszone)
+	b = binary.LittleEndian.AppendUint32(b, 0) // Reserved (formerly sigfigs)
+	b = binary.LittleEndian.AppendUint32(b, pcapSnapLen)
+	b = binary.LittleEndian.AppendUint32(b, linkTypeRaw)
+	if _, err := w.Write(b); err != nil {
+		return nil, fmt.Errorf("failed to write capture file header: %w", err)
+	}
+	return &pcapWriter{w: w}, nil
+}
+
+func (pw *pcapWriter) WriteRecord(rec CaptureRecord) error {
+	b := append(pw.buf[:0], make([]byte, 16)...)
+	b, err := appendDatagram(b, &rec)
+	if err != nil {
+		return err
+	}
+	ns := rec.Time.UnixNano()
+	binary.LittleEndian.PutUint32(b[0:], uint32(ns/1e9))
+	binary.LittleEndian.PutUint32(b[4:], uint32(ns%1e9))
+	binary.LittleEndian.PutUint32(b[8:], uint32(len(b)-16))
+	binary.LittleEndian.PutUint32(b[12:], uint32(len(b)-16))
+	pw.buf = b
+	if _, err := pw.w.Write(b); err != nil {
+		return fmt.Errorf("failed to write capture record: %w", err)
+	}
+	return nil
+}
+
+// pcapReader reads libpcap files of either byte order and timestamp resolution.
+type pcapReader struct {
+	r        io.Reader
+	order    binary.ByteOrder
+	nano     bool
+	linkType uint32
+	hdr      [16]byte
+	frame    []byte
+}
+
+func newPcapReader(r io.Reader) (*pcapReader, error) {
+	var hdr [24]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, fmt.Errorf("failed to read capture file header: %w", err)
+	}
+	pr := &pcapReader{r: r, order: binary.LittleEndian}
+	magic := binary.LittleEndian.Uint32(hdr[:])
+	switch magic {
+	case pcapMagicMicro, pcapMagicNano:
+	default:
+		pr.order = binary.BigEndian
+		magic = binary.BigEndian.Uint32(hdr[:])
+		if magic != pcapMagicMicro && magic != pcapMagicNano {
+			return nil, fmt.Errorf("unknown capture file format (magic %#08x)", magic)
+		}
+	}
+	pr.nano = magic == pcapMagicNano
+	pr.linkType = pr.order.Uint32(hdr[20:]) & 0xFFFF // The upper bits hold FCS information
+	return pr, nil
+}
+
+func (pr *pcapReader) ReadRecord() (CaptureRecord, error) {
+	for {
+		var rec CaptureRecord
+		if _, err := io.ReadFull(pr.r, pr.hdr[:]); err != nil {
+			return rec, err
+		}
+		sec := int64(pr.order.Uint32(pr.hdr[0:]))
+		frac := int64(pr.order.Uint32(pr.hdr[4:]))
+		size := int(pr.order.Uint32(pr.hdr[8:]))
+		if size > pcapngMaxBlock {
+			return rec, fmt.Errorf("invalid capture record length: %d", size)
+		}
+		if cap(pr.frame) < size {
+			pr.frame = make([]byte, size)
+		}
+		pr.frame = pr.frame[:size]
+		if _, err := io.ReadFull(pr.r, pr.frame); err != nil {
+			return rec, truncated(err)
+		}
+		if !pr.nano {
+			frac *= 1000
+		}
+		rec.Time = time.Unix(sec, frac)
+		if parseDatagram(pr.linkType, pr.frame, pr.order, &rec) {
+			return rec, nil
+		}
+	}
+}
+
+// pcapngWriter writes pcapng files with a single raw IP interface and nanosecond
+// timestamps.
+type pcapngWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+func newPcapNGWriter(w io.Writer) (*pcapngWriter, error) {
+	le := binary.LittleEndian
+	// Section Header Block without options.
+	b := le.AppendUint32(nil, pcapngSHB)
+	b = le.AppendUint32(b, 28)
+	b = le.AppendUint32(b, pcapngByteOrderMagic)
+	b = le.AppendUint16(b, 1) // Version 1.0
+	b = le.AppendUint16(b, 0)
+	b = le.AppendUint64(b, ^uint64(0)) // Section length not specified
+	b = le.AppendUint32(b, 28)
+	// Interface Description Block with if_tsresol = 10^-9.
+	b = le.AppendUint32(b, pcapngIDB)
+	b = le.AppendUint32(b, 32)
+	b = le.AppendUint16(b, linkTypeRaw)
+	b = le.AppendUint16(b, 0)
+	b = le.AppendUint32(b, pcapSnapLen)
+	b = le.AppendUint16(b, pcapngOptTSResol)
+	b = le.AppendUint16(b, 1)
+	b = append(b, 9, 0, 0, 0)
+	b = le.AppendUint32(b, 0) // opt_endofopt
+	b = le.AppendUint32(b, 32)
+	if _, err := w.Write(b); err != nil {
+		return nil, fmt.Errorf("failed to write capture file header: %w", err)
+	}
+	return &pcapngWriter{w: w}, nil
+}
+
+func (pw *pcapngWriter) WriteRecord(rec CaptureRecord) error {
+	le := binary.LittleEndian
+	b := append(pw.buf[:0], make([]byte, 28)...)
+	b, err := appendDatagram(b, &rec)
+	if err != nil {
+		return err
+	}
+	size := len(b) - 28
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	b = le.AppendUint32(b, uint32(len(b)+4))
+	ns := uint64(rec.Time.UnixNano())
+	le.PutUint32(b[0:], pcapngEPB)
+	le.PutUint32(b[4:], uint32(len(b)))
+	le.PutUint32(b[8:], 0) // Interface ID
+	le.PutUint32(b[12:], uint32(ns>>32))
+	le.PutUint32(b[16:], uint32(ns))
+	le.PutUint32(b[20:], uint32(size))
+	le.PutUint32(b[24:], uint32(size))
+	pw.buf = b
+	if _, err := pw.w.Write(b); err != nil {
+		return fmt.Errorf("failed to write capture record: %w", err)
+	}
+	return nil
+}
+
+// pcapngInterface describes an interface of a pcapng section.
+type pcapngInterface struct {
+	linkType uint32
+	units    uint64 // Timestamp units per second
+}
+
+// pcapngReader reads pcapng files with any number of sections and interfaces.
+type pcapngReader struct {
+	r      io.Reader
+	order  binary.ByteOrder
+	ifaces []pcapngInterface
+	block  []byte
+}
+
+func newPcapNGReader(r io.Reader) (*pcapngReader, error) {
+	return &pcapngReader{r: r, order: binary.LittleEndian}, nil
+}
+
+func (pr *pcapngReader) ReadRecord() (CaptureRecord, error) {
+	for {
+		var rec CaptureRecord
+		var hdr [8]byte
+		if _, err := io.ReadFull(pr.r, hdr[:]); err != nil {
+			return rec, err
+		}
+		typ := pr.order.Uint32(hdr[0:])
+		if binary.LittleEndian.Uint32(hdr[0:]) == pcapngSHB {
+			typ = pcapngSHB // The SHB type is a palindrome; its byte order is read from the body
+		}
+		if typ == pcapngSHB {
+			var bom [4]byte
+			if _, err := io.ReadFull(pr.r, bom[:]); err != nil {
+				return rec, truncated(err)
+			}
+			switch uint32(pcapngByteOrderMagic) {
+			case binary.LittleEndian.Uint32(bom[:]):
+				pr.order = binary.LittleEndian
+			case binary.BigEndian.Uint32(bom[:]):
+				pr.order = binary.BigEndian
+			default:
+				return rec, fmt.Errorf("invalid pcapng byte-order magic")
+			}
+			pr.ifaces = pr.ifaces[:0]
+			if err := pr.readBody(pr.order.Uint32(hdr[4:]), 12); err != nil {
+				return rec, err
+			}
+			continue
+		}
+		if err := pr.readBody(pr.order.Uint32(hdr[4:]), 8); err != nil {
+			return rec, err
+		}
+		body := pr.block
+		switch typ {
+		case pcapngIDB:
+			if len(body) < 8 {
+				return rec, fmt.Errorf("invalid pcapng interface block")
+			}
+			iface := pcapngInterface{linkType: uint32(pr.order.Uint16(body)), units: 1e6}
+			opts := body[8:]
+			for len(opts) >= 4 {
+				code, size := pr.order.Uint16(opts), int(pr.order.Uint16(opts[2:]))
+				if code == 0 || 4+size > len(opts) {
+					break
+				}
+				if code == pcapngOptTSResol && size >= 1 {
+					res := opts[4]
+					base := uint64(10)
+					if res&0x80 != 0 {
+						base = 2
+					}
+					iface.units = 1
+					for range res & 0x7F {
+						iface.units *= base
+					}
+				}
+				opts = opts[4+(size+3)&^3:]
+			}
+			pr.ifaces = append(pr.ifaces, iface)
+		case pcapngEPB:
+			if len(body) < 20 {
+				return rec, fmt.Errorf("invalid pcapng packet block")
+			}
+			id := int(pr.order.Uint32(body))
+			size := int(pr.order.Uint32(body[12:]))
+			if id >= len(pr.ifaces) || 20+size > len(body) {
+				return rec, fmt.Errorf("invalid pcapng packet block")
+			}
+			iface := pr.ifaces[id]
+			ts := uint64(pr.order.Uint32(body[4:]))<<32 | uint64(pr.order.Uint32(body[8:]))
+			sec, frac := ts/iface.units, ts%iface.units
+			rec.Time = time.Unix(int64(sec), int64(frac*1e9/iface.units))
+			if parseDatagram(iface.linkType, body[20:20+size], pr.order, &rec) {
+				return rec, nil
+			}
+		case pcapngSPB:
+			// Simple packets carry no timestamp; they are returned with the zero time.
+			if len(body) < 4 || len(pr.ifaces) == 0 {
+				continue
+			}
+			size := min(int(pr.order.Uint32(body)), len(body)-4)
+			if parseDatagram(pr.ifaces[0].linkType, body[4:4+size], pr.order, &rec) {
+				return rec, nil
+			}
+		}
+	}
+}
+
+// readBody reads the rest of a block of the given total length, of which read bytes have
+// already been consumed, into pr.block (without the trailing length).
+func (pr *pcapngReader) readBody(total uint32, read int) error {
+	if total%4 != 0 || int(total) < read+4 || total > pcapngMaxBlock {
+		return fmt.Errorf("invalid pcapng block length: %d", total)
+	}
+	size := int(total) - read
+	if cap(pr.block) < size {
+		pr.block = make([]byte, size)
+	}
+	pr.block = pr.block[:size]
+	if _, err := io.ReadFull(pr.r, pr.block); err != nil {
+		return truncated(err)
+	}
+	pr.block = pr.block[:size-4]
+	return nil
+}
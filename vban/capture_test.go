@@ -0,0 +1,169 @@
+package vban
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+// testRecords returns capture records with IPv4 and IPv6 addresses and packets of
+// different sizes, including odd lengths.
+func testRecords(tb testing.TB) []CaptureRecord {
+	tb.Helper()
+	var recs []CaptureRecord
+	start := time.Unix(1700000000, 123456789)
+	addrs := [][2]string{
+		{"192.168.1.10:6980", "192.168.1.20:6980"},
+		{"[fe80::1]:50000", "[fe80::2]:6980"},
+		{"10.0.0.1:1234", "255.255.255.255:6980"},
+	}
+	for i, a := range addrs {
+		p, err := NewPacket(NewHeader(ProtocolText, "Stream1"), bytes.Repeat([]byte{byte(i + 1)}, 2*i+1))
+		if err != nil {
+			tb.Fatal(err)
+		}
+		p.Header.NuFrame = uint32(i)
+		data, err := p.MarshalBinary()
+		if err != nil {
+			tb.Fatal(err)
+		}
+		recs = append(recs, CaptureRecord{
+			Time: start.Add(time.Duration(i) * 1001 * time.Microsecond),
+			Src:  netip.MustParseAddrPort(a[0]),
+			Dst:  netip.MustParseAddrPort(a[1]),
+			Data: data,
+		})
+	}
+	return recs
+}
+
+// readAll reads all records of a capture file, copying their data.
+func readAll(tb testing.TB, file []byte) []CaptureRecord {
+	tb.Helper()
+	r, err := NewCaptureReader(bytes.NewReader(file))
+	if err != nil {
+		tb.Fatalf("NewCaptureReader: %v", err)
+	}
+	var recs []CaptureRecord
+	for {
+		rec, err := r.ReadRecord()
+		if errors.Is(err, io.EOF) {
+			return recs
+		}
+		if err != nil {
+			tb.Fatalf("ReadRecord: %v", err)
+		}
+		rec.Data = bytes.Clone(rec.Data)
+		recs = append(recs, rec)
+	}
+}
+
+// checkRecords compares the records read back with the records written.
+func checkRecords(t *testing.T, got, want []CaptureRecord) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("read %d records, want %d", len(got), len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if !g.Time.Equal(w.Time) || g.Src != w.Src || g.Dst != w.Dst || !bytes.Equal(g.Data, w.Data) {
+			t.Errorf("record %d: got %v %v->%v % X, want %v %v->%v % X",
+				i, g.Time, g.Src, g.Dst, g.Data, w.Time, w.Src, w.Dst, w.Data)
+		}
+	}
+}
+
+func TestCaptureRoundTrip(t *testing.T) {
+	formats := map[string]CaptureFormat{"native": CaptureNative, "pcap": CapturePcap, "pcapng": CapturePcapNG}
+	for name, format := range formats {
+		t.Run(name, func(t *testing.T) {
+			want := testRecords(t)
+			var buf bytes.Buffer
+			w, err := NewCaptureWriter(&buf, format)
+			if err != nil {
+				t.Fatalf("NewCaptureWriter: %v", err)
+			}
+			for _, rec := range want {
+				if err := w.WriteRecord(rec); err != nil {
+					t.Fatalf("WriteRecord: %v", err)
+				}
+			}
+			checkRecords(t, readAll(t, buf.Bytes()), want)
+		})
+	}
+}
+
+func TestCaptureNativeWithoutDestination(t *testing.T) {
+	want := testRecords(t)[:1]
+	want[0].Dst = netip.AddrPort{}
+	var buf bytes.Buffer
+	w, err := NewCaptureWriter(&buf, CaptureNative)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRecord(want[0]); err != nil {
+		t.Fatalf("WriteRecord: %v", err)
+	}
+	checkRecords(t, readAll(t, buf.Bytes()), want)
+}
+
+func TestAppendDatagramChecksums(t *testing.T) {
+	for i, rec := range testRecords(t) {
+		b, err := appendDatagram(nil, &rec)
+		if err != nil {
+			t.Fatalf("record %d: appendDatagram: %v", i, err)
+		}
+		src, dst := rec.Src.Addr().AsSlice(), rec.Dst.Addr().AsSlice()
+		udp := b[40:]
+		if rec.Src.Addr().Is4() {
+			if sum := checksum(0, b[:20]); sum != 0xFFFF {
+				t.Errorf("record %d: IPv4 header checksum does not verify (sum %#04x)", i, sum)
+			}
+			udp = b[20:]
+		}
+		// The UDP checksum over the pseudo-header and the datagram must verify as well.
+		sum := uint32(checksum(0, src)) + uint32(checksum(0, dst)) + ipProtoUDP + uint32(len(udp))
+		if got := checksum(sum, udp); got != 0xFFFF {
+			t.Errorf("record %d: UDP checksum does not verify (sum %#04x)", i, got)
+		}
+	}
+}
+
+func TestCaptureReaderEthernetMicroBigEndian(t *testing.T) {
+	rec := testRecords(t)[0]
+	ip, err := appendDatagram(nil, &rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	be := binary.BigEndian
+	file := be.AppendUint32(nil, pcapMagicMicro)
+	file = be.AppendUint16(file, 2)
+	file = be.AppendUint16(file, 4)
+	file = append(file, make([]byte, 8)...)
+	file = be.AppendUint32(file, pcapSnapLen)
+	file = be.AppendUint32(file, linkTypeEthernet)
+
+	appendFrame := func(frame []byte) {
+		file = be.AppendUint32(file, uint32(rec.Time.Unix()))
+		file = be.AppendUint32(file, uint32(rec.Time.Nanosecond()/1000))
+		file = be.AppendUint32(file, uint32(len(frame)))
+		file = be.AppendUint32(file, uint32(len(frame)))
+		file = append(file, frame...)
+	}
+	macs := make([]byte, 12)
+	// An ARP frame, which is skipped.
+	appendFrame(append(be.AppendUint16(bytes.Clone(macs), 0x0806), make([]byte, 28)...))
+	// The datagram behind a VLAN tag, with Ethernet padding.
+	frame := be.AppendUint16(bytes.Clone(macs), 0x8100)
+	frame = append(frame, 0x00, 0x05, 0x08, 0x00)
+	frame = append(frame, ip...)
+	appendFrame(append(frame, 0, 0, 0, 0))
+
+	want := rec
+	want.Time = want.Time.Truncate(time.Microsecond)
+	checkRecords(t, readAll(t, file), []CaptureRecord{want})
+}
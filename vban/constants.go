@@ -1,6 +1,9 @@
 package vban
 
-import "encoding/binary"
+import (
+	"encoding/binary"
+	"fmt"
+)
 
 // Endianness for VBAN protocol (Little Endian)
 var byteOrder = binary.LittleEndian
@@ -43,6 +46,22 @@ func (sp SubProtocol) IsText() bool { return (sp & ProtocolMask) == ProtocolText
 // IsService checks if the sub-protocol is Service.
 func (sp SubProtocol) IsService() bool { return (sp & ProtocolMask) == ProtocolService }
 
+// String returns the name of the sub-protocol, e.g. "AUDIO".
+func (sp SubProtocol) String() string {
+	switch sp & ProtocolMask {
+	case ProtocolAudio:
+		return "AUDIO"
+	case ProtocolSerial:
+		return "SERIAL"
+	case ProtocolText:
+		return "TEXT"
+	case ProtocolService:
+		return "SERVICE"
+	default:
+		return fmt.Sprintf("USER(%#02x)", uint8(sp&ProtocolMask))
+	}
+}
+
 // --- Sample Rate Index (Audio) / BPS Index (Serial/Text) (Spec p.8, p.14, p.19) ---
 
 // SRIndex represents the Sample Rate index (Audio) or BPS index (Serial/Text).
@@ -89,6 +108,17 @@ func (sri SRIndex) GetRate(sp SubProtocol) uint32 {
 	return 0 // Undefined index or protocol for rate lookup
 }
 
+// FindSRIndex returns the SRIndex corresponding to the given audio sample rate in Hz.
+// Returns an error if the rate is not defined by the specification.
+func FindSRIndex(rate uint32) (SRIndex, error) {
+	for index, sr := range SRList {
+		if sr == rate {
+			return index, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported sample rate for VBAN: %d Hz", rate)
+}
+
 // --- Data Type (Spec p.9) ---
 
 // DataType defines the format of individual data samples or elements.
@@ -108,24 +138,78 @@ const (
 	// Bit 3 (0x08) is reserved, should be 0 for standard types.
 )
 
-// Size returns the size in bytes for the DataType.
-// Returns 0 for types requiring special handling (INT24, 12BIT, 10BIT)
-// as their byte representation isn't straightforward.
+// Size returns the size in bytes of a single sample for the DataType.
+// Returns 0 for the bit-packed types (12BIT, 10BIT) whose samples do not
+// occupy a whole number of bytes; use BitsPerSample or PayloadSize for those.
 func (dt DataType) Size() int {
 	switch dt & DataTypeMask {
 	case DataTypeUINT8:
 		return 1
 	case DataTypeINT16:
 		return 2
+	case DataTypeINT24:
+		return 3
 	case DataTypeINT32, DataTypeFLOAT32:
 		return 4
 	case DataTypeFLOAT64:
 		return 8
-	case DataTypeINT24, DataType12BIT, DataType10BIT:
-		return 0 // Special handling required
+	case DataType12BIT, DataType10BIT:
+		return 0 // Bit-packed, see BitsPerSample
+	default:
+		return 0
+	}
+}
+
+// BitsPerSample returns the number of bits a single sample occupies in the payload.
+func (dt DataType) BitsPerSample() int {
+	switch dt & DataTypeMask {
+	case DataType12BIT:
+		return 12
+	case DataType10BIT:
+		return 10
 	default:
+		return dt.Size() * 8
+	}
+}
+
+// PayloadSize returns the number of payload bytes needed to hold nbSamples
+// samples (counted across all channels) of this DataType.
+// Bit-packed types are rounded up to the next whole byte.
+func (dt DataType) PayloadSize(nbSamples int) int {
+	if nbSamples <= 0 {
+		return 0
+	}
+	return (nbSamples*dt.BitsPerSample() + 7) / 8
+}
+
+// String returns the name of the DataType, e.g. "INT16".
+func (dt DataType) String() string {
+	switch dt & DataTypeMask {
+	case DataTypeUINT8:
+		return "UINT8"
+	case DataTypeINT16:
+		return "INT16"
+	case DataTypeINT24:
+		return "INT24"
+	case DataTypeINT32:
+		return "INT32"
+	case DataTypeFLOAT32:
+		return "FLOAT32"
+	case DataTypeFLOAT64:
+		return "FLOAT64"
+	case DataType12BIT:
+		return "12BIT"
+	default:
+		return "10BIT"
+	}
+}
+
+// SampleCount returns the number of whole samples contained in payloadLen bytes.
+func (dt DataType) SampleCount(payloadLen int) int {
+	if payloadLen <= 0 {
 		return 0
 	}
+	return payloadLen * 8 / dt.BitsPerSample()
 }
 
 // --- Codec (Audio) / Serial Type / Text Format (Spec p.10, p.16, p.20) ---
@@ -0,0 +1,376 @@
+package vban
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultWatchInterval is the ping interval used when WatcherConfig.Interval is zero.
+const DefaultWatchInterval = 5 * time.Second
+
+// Device is a VBAN device found on the network through the Identification service.
+type Device struct {
+	Addr      *net.UDPAddr  // Address the device replied from
+	Info      PingPacket    // Device information from the latest reply
+	Streams   []string      // Names of the streams recently received from the device's IP
+	RTT       time.Duration // Round-trip time of the latest ping
+	FirstSeen time.Time     // Time of the first reply
+	LastSeen  time.Time     // Time of the latest reply
+}
+
+// Capabilities returns the features announced by the device.
+func (d *Device) Capabilities() PingFeature { return d.Info.Features }
+
+// clone returns a copy of the device that does not share the Streams slice.
+func (d *Device) clone() Device {
+	c := *d
+	c.Streams = slices.Clone(d.Streams)
+	return c
+}
+
+// broadcastTarget fills in defaults for a discovery destination.
+func broadcastTarget(addr *net.UDPAddr) *net.UDPAddr {
+	if addr == nil {
+		return &net.UDPAddr{IP: net.IPv4bcast, Port: DefaultPort}
+	}
+	if addr.Port == 0 {
+		a := *addr
+		a.Port = DefaultPort
+		return &a
+	}
+	return addr
+}
+
+// Discover broadcasts an Identification request to broadcastAddr (255.255.255.255:6980 if nil)
+// and returns the devices that replied within timeout, one entry per reply address.
+// It uses its own socket bound to an ephemeral port. If ctx is canceled before the timeout
+// expires, the devices found so far are returned together with ctx.Err().
+//
+// Since devices do not send their streams to that socket, the devices' Streams are
+// normally empty; use DiscoverConn with a Conn bound to the port the streams are sent to
+// in order to learn them.
+func Discover(ctx context.Context, broadcastAddr *net.UDPAddr, timeout time.Duration) ([]Device, error) {
+	network := "udp4"
+	if broadcastTarget(broadcastAddr).IP.To4() == nil {
+		network = "udp6"
+	}
+	udpConn, err := net.ListenUDP(network, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open discovery socket: %w", err)
+	}
+	conn := NewConn(udpConn)
+	defer conn.Close()
+	return DiscoverConn(ctx, conn, broadcastAddr, timeout)
+}
+
+// DiscoverConn is like Discover but pings and listens on conn, enabling sending to
+// broadcast addresses on it if broadcastAddr is an IPv4 address. The names of the streams
+// received on conn during the timeout are recorded in the Streams of the devices they were
+// sent from, so a Conn bound to the VBAN port (e.g. 6980) also reports the streams each
+// device sends to this host. DiscoverConn reads from conn itself, so conn should not be
+// read concurrently (e.g. by a Mux).
+func DiscoverConn(ctx context.Context, conn *Conn, broadcastAddr *net.UDPAddr, timeout time.Duration) ([]Device, error) {
+	if conn == nil {
+		return nil, ErrClosed
+	}
+	target := broadcastTarget(broadcastAddr)
+	if target.IP.To4() != nil {
+		if err := conn.SetBroadcast(true); err != nil {
+			return nil, err
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	var devices []Device
+	index := make(map[string]int)
+	streams := make(map[string]map[string]bool) // Source IP -> stream names
+	err := ping(ctx, conn, target, nil, deadline, func(reply PingReply) {
+		now := time.Now()
+		key := reply.Addr.String()
+		if i, ok := index[key]; ok {
+			devices[i].Info = reply.Info
+			devices[i].LastSeen = now
+			return
+		}
+		index[key] = len(devices)
+		devices = append(devices, Device{
+			Addr:      reply.Addr,
+			Info:      reply.Info,
+			RTT:       reply.RTT,
+			FirstSeen: now,
+			LastSeen:  now,
+		})
+	}, func(p *Packet, addr *net.UDPAddr) {
+		ip := addr.IP.String()
+		if streams[ip] == nil {
+			streams[ip] = make(map[string]bool)
+		}
+		streams[ip][p.Header.GetStreamName()] = true
+	})
+	for i := range devices {
+		if names := streams[devices[i].Addr.IP.String()]; len(names) > 0 {
+			devices[i].Streams = slices.Sorted(maps.Keys(names))
+		}
+	}
+	return devices, err
+}
+
+// DeviceEventType identifies the kind of change reported by a Watcher.
+type DeviceEventType int
+
+const (
+	DeviceAdded   DeviceEventType = iota // A device replied for the first time
+	DeviceUpdated                        // A device's information or streams changed
+	DeviceRemoved                        // A device stopped replying
+)
+
+// String returns the name of the event type.
+func (t DeviceEventType) String() string {
+	switch t {
+	case DeviceAdded:
+		return "added"
+	case DeviceUpdated:
+		return "updated"
+	case DeviceRemoved:
+		return "removed"
+	default:
+		return fmt.Sprintf("DeviceEventType(%d)", int(t))
+	}
+}
+
+// DeviceEvent reports a change in the set of devices tracked by a Watcher.
+type DeviceEvent struct {
+	Type   DeviceEventType
+	Device Device
+}
+
+// WatcherConfig configures a Watcher.
+type WatcherConfig struct {
+	BroadcastAddr *net.UDPAddr      // Ping destination (255.255.255.255:6980 if nil)
+	Interval      time.Duration     // Time between pings (DefaultWatchInterval if zero)
+	Expiry        time.Duration     // Devices and streams not seen for this long are removed (3*Interval if zero)
+	OnEvent       func(DeviceEvent) // Called for every change; must not block for long
+}
+
+// Watcher continuously discovers VBAN devices by pinging periodically and reports devices
+// appearing, changing and disappearing. It also records the names of streams sent from each
+// device's IP address.
+//
+// Watcher is a Handler: it must be fed the packets received on its Conn, either by a Mux
+// (e.g. as the unmatched handler, to also learn stream names) or by calling Serve.
+// Run drives the periodic pings. Watcher is safe for concurrent use.
+type Watcher struct {
+	conn *Conn
+	cfg  WatcherConfig
+
+	mu       sync.Mutex
+	devices  map[string]*Device              // Keyed by reply address
+	streams  map[string]map[string]time.Time // Source IP -> stream name -> last seen
+	requests map[uint32]time.Time            // Outstanding request IDs -> send time
+	nextID   uint32
+}
+
+// NewWatcher creates a Watcher sending pings on conn. Sending to broadcast addresses is
+// enabled on conn if the ping destination is an IPv4 address.
+func NewWatcher(conn *Conn, cfg WatcherConfig) (*Watcher, error) {
+	if conn == nil {
+		return nil, ErrClosed
+	}
+	if _, err := conn.conn(); err != nil {
+		return nil, err
+	}
+	cfg.BroadcastAddr = broadcastTarget(cfg.BroadcastAddr)
+	if cfg.BroadcastAddr.IP.To4() != nil {
+		if err := conn.SetBroadcast(true); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultWatchInterval
+	}
+	if cfg.Expiry <= 0 {
+		cfg.Expiry = 3 * cfg.Interval
+	}
+	return &Watcher{
+		conn:     conn,
+		cfg:      cfg,
+		devices:  make(map[string]*Device),
+		streams:  make(map[string]map[string]time.Time),
+		requests: make(map[uint32]time.Time),
+		nextID:   rand.Uint32(),
+	}, nil
+}
+
+// emit delivers events to the OnEvent callback. It must be called without w.mu held.
+func (w *Watcher) emit(events []DeviceEvent) {
+	if w.cfg.OnEvent == nil {
+		return
+	}
+	for _, ev := range events {
+		w.cfg.OnEvent(ev)
+	}
+}
+
+// streamNames returns the sorted stream names recorded for ip. It must be called with w.mu held.
+func (w *Watcher) streamNames(ip string) []string {
+	var names []string
+	for name := range w.streams[ip] {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// HandlePacket records ping replies and the stream names of all other packets.
+func (w *Watcher) HandlePacket(p *Packet, addr *net.UDPAddr) {
+	if p == nil || addr == nil {
+		return
+	}
+	now := time.Now()
+	var events []DeviceEvent
+	w.mu.Lock()
+	if p.Header.SubProtocol().IsService() {
+		if isPing(p, true) {
+			events = w.handleReply(p, addr, now)
+		}
+	} else {
+		ip := addr.IP.String()
+		names, ok := w.streams[ip]
+		if !ok {
+			names = make(map[string]time.Time)
+			w.streams[ip] = names
+		}
+		name := p.Header.GetStreamName()
+		_, known := names[name]
+		names[name] = now
+		if !known {
+			for _, d := range w.devices {
+				if d.Addr.IP.Equal(addr.IP) {
+					d.Streams = w.streamNames(ip)
+					events = append(events, DeviceEvent{Type: DeviceUpdated, Device: d.clone()})
+				}
+			}
+		}
+	}
+	w.mu.Unlock()
+	w.emit(events)
+}
+
+// handleReply updates the device list from a ping reply. It must be called with w.mu held.
+func (w *Watcher) handleReply(p *Packet, addr *net.UDPAddr, now time.Time) []DeviceEvent {
+	sent, ok := w.requests[p.Header.NuFrame]
+	if !ok {
+		return nil // Not a reply to one of our requests
+	}
+	var info PingPacket
+	if err := info.UnmarshalBinary(p.Data); err != nil {
+		return nil
+	}
+	key := addr.String()
+	d, ok := w.devices[key]
+	if !ok {
+		d = &Device{
+			Addr:      addr,
+			Info:      info,
+			Streams:   w.streamNames(addr.IP.String()),
+			RTT:       now.Sub(sent),
+			FirstSeen: now,
+			LastSeen:  now,
+		}
+		w.devices[key] = d
+		return []DeviceEvent{{Type: DeviceAdded, Device: d.clone()}}
+	}
+	d.RTT = now.Sub(sent)
+	d.LastSeen = now
+	if d.Info != info {
+		d.Info = info
+		return []DeviceEvent{{Type: DeviceUpdated, Device: d.clone()}}
+	}
+	return nil
+}
+
+// Serve reads packets from the Watcher's Conn until the connection is closed (returning nil)
+// or fails. Use a Mux instead if the Conn also carries other traffic.
+func (w *Watcher) Serve() error {
+	return serve(w.conn, w)
+}
+
+// Run sends a ping immediately and then every Interval, and removes devices and streams
+// not seen within Expiry, until ctx is canceled. It returns ctx.Err().
+func (w *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		w.tick(time.Now())
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick sends one ping and expires stale entries.
+func (w *Watcher) tick(now time.Time) {
+	var events []DeviceEvent
+	w.mu.Lock()
+	for id, sent := range w.requests {
+		if now.Sub(sent) > w.cfg.Expiry {
+			delete(w.requests, id)
+		}
+	}
+	for key, d := range w.devices {
+		if now.Sub(d.LastSeen) > w.cfg.Expiry {
+			delete(w.devices, key)
+			events = append(events, DeviceEvent{Type: DeviceRemoved, Device: d.clone()})
+		}
+	}
+	for ip, names := range w.streams {
+		for name, seen := range names {
+			if now.Sub(seen) > w.cfg.Expiry {
+				delete(names, name)
+			}
+		}
+		if len(names) == 0 {
+			delete(w.streams, ip)
+		}
+	}
+	for _, d := range w.devices {
+		if names := w.streamNames(d.Addr.IP.String()); !slices.Equal(names, d.Streams) {
+			d.Streams = names
+			events = append(events, DeviceEvent{Type: DeviceUpdated, Device: d.clone()})
+		}
+	}
+	id := w.nextID
+	w.nextID++
+	w.requests[id] = now
+	w.mu.Unlock()
+	w.emit(events)
+
+	if request, err := NewPingPacket(ServiceFuncPing, id, nil); err == nil {
+		_ = w.conn.Send(request, w.cfg.BroadcastAddr) // Retried on the next tick
+	}
+}
+
+// Devices returns a snapshot of the devices currently known, sorted by address.
+func (w *Watcher) Devices() []Device {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	devices := make([]Device, 0, len(w.devices))
+	for _, d := range w.devices {
+		devices = append(devices, d.clone())
+	}
+	slices.SortFunc(devices, func(a, b Device) int {
+		if c := slices.Compare(a.Addr.IP, b.Addr.IP); c != 0 {
+			return c
+		}
+		return a.Addr.Port - b.Addr.Port
+	})
+	return devices
+}
@@ -0,0 +1,253 @@
+package vban
+
+import (
+	"errors"
+	"io"
+	"math"
+	"time"
+)
+
+// DefaultDriftTimeConstant is the averaging time constant used when NewDriftEstimator is
+// given zero.
+const DefaultDriftTimeConstant = 30 * time.Second
+
+// driftMinSpan is the observation span required before a drift estimate is reported.
+const driftMinSpan = 2 * time.Second
+
+// DriftEstimator estimates the actual sample rate of a remote sender, measured against the
+// local clock, from the arrival times of its packets. Each packet's position in the stream
+// (NuFrame * SamplesPerFrame) is regressed against its arrival time with exponentially
+// decaying weights, so network jitter averages out while slow clock variations are
+// tracked. DriftEstimator is not safe for concurrent use.
+type DriftEstimator struct {
+	nominal float64 // Nominal sample rate (Hz)
+	tau     float64 // Averaging time constant (s)
+
+	started bool
+	lastNu  uint32    // NuFrame of the latest packet
+	origin  time.Time // Arrival time of the latest packet (regression origin)
+	span    float64   // Time covered by the observations (s)
+
+	// Exponentially weighted sums, relative to the latest packet (x: seconds, y: samples).
+	sw, sx, sy, sxx, sxy float64
+}
+
+// NewDriftEstimator creates an estimator for a stream with the given nominal sample rate.
+// timeConstant controls how quickly old observations are forgotten
+// (DefaultDriftTimeConstant if zero).
+func NewDriftEstimator(nominalRate uint32, timeConstant time.Duration) *DriftEstimator {
+	if timeConstant <= 0 {
+		timeConstant = DefaultDriftTimeConstant
+	}
+	return &DriftEstimator{nominal: float64(nominalRate), tau: timeConstant.Seconds()}
+}
+
+// Reset discards all observations, e.g. after the sender restarted.
+func (d *DriftEstimator) Reset() {
+	*d = DriftEstimator{nominal: d.nominal, tau: d.tau}
+}
+
+// Update records the arrival of the audio frame nuFrame carrying samplesPerFrame samples
+// per channel. Frames may arrive out of order; duplicates should not be passed in.
+func (d *DriftEstimator) Update(nuFrame uint32, samplesPerFrame int, arrival time.Time) {
+	if !d.started {
+		d.started = true
+		d.lastNu = nuFrame
+		d.origin = arrival
+		d.sw = 1
+		return
+	}
+	// Move the origin to the new observation, decaying the old ones.
+	dx := arrival.Sub(d.origin).Seconds()
+	dy := float64(int32(nuFrame-d.lastNu)) * float64(samplesPerFrame)
+	d.sxx += -2*dx*d.sx + d.sw*dx*dx
+	d.sxy += -dx*d.sy - dy*d.sx + d.sw*dx*dy
+	d.sx -= d.sw * dx
+	d.sy -= d.sw * dy
+	if dx > 0 {
+		decay := math.Exp(-dx / d.tau)
+		d.sw *= decay
+		d.sx *= decay
+		d.sy *= decay
+		d.sxx *= decay
+		d.sxy *= decay
+		d.span += dx
+	}
+	// Add the new observation at (0, 0).
+	d.sw++
+	d.lastNu = nuFrame
+	d.origin = arrival
+}
+
+// Observe records the arrival of an audio packet, using its NuFrame and SamplesPerFrame.
+func (d *DriftEstimator) Observe(h *Header, arrival time.Time) {
+	d.Update(h.NuFrame, int(h.FormatNbs)+1, arrival)
+}
+
+// Rate returns the estimated sample rate of the sender in samples per second of the local
+// clock. ok is false until enough observations have been collected.
+func (d *DriftEstimator) Rate() (rate float64, ok bool) {
+	if d.span < driftMinSpan.Seconds() {
+		return 0, false
+	}
+	den := d.sw*d.sxx - d.sx*d.sx
+	if den <= 0 {
+		return 0, false
+	}
+	return (d.sw*d.sxy - d.sx*d.sy) / den, true
+}
+
+// Ratio returns the estimated sender rate relative to the nominal rate, e.g. 1.0001 for a
+// sender running 100 ppm fast. It returns 1 until an estimate is available.
+func (d *DriftEstimator) Ratio() float64 {
+	rate, ok := d.Rate()
+	if !ok || d.nominal == 0 {
+		return 1
+	}
+	return rate / d.nominal
+}
+
+// PPM returns the estimated drift of the sender in parts per million (0 if unknown).
+func (d *DriftEstimator) PPM() float64 {
+	return (d.Ratio() - 1) * 1e6
+}
+
+// Drift compensation control parameters.
+const (
+	driftKp          = 0.1   // Proportional gain: correction per second of buffer error
+	driftKi          = 0.002 // Integral gain: correction per second of accumulated error per second
+	driftLevelTau    = 1.0   // Smoothing time constant of the measured buffer level (s)
+	defaultMaxDrift  = 0.001 // Default bound of the ratio correction (1000 ppm)
+	driftMinInterval = 0.001 // Minimum time between controller updates (s)
+)
+
+// DriftCompensatorConfig configures a DriftCompensator.
+type DriftCompensatorConfig struct {
+	OutputRate    uint32          // Output sample rate in Hz (the stream's rate if zero)
+	Quality       ResampleQuality // Quality of the resampling filter
+	MaxCorrection float64         // Bound of the relative rate correction (0.001, i.e. 1000 ppm, if zero)
+}
+
+// DriftCompensator reads from an AudioReceiver through a Resampler whose ratio is
+// continuously fine-tuned so that the receiver's jitter buffer stays at its target
+// latency. The sender's clock drift, as estimated by the receiver, is applied directly,
+// and a PI controller on the buffer fill removes the remaining error. This prevents the
+// periodic underflows and overflows (and the resulting clicks) of long-running streams
+// whose sender and receiver clocks differ slightly. The correction is bounded by
+// MaxCorrection to keep pitch changes inaudible, so a large initial offset from the target
+// level is recovered gradually.
+//
+// The compensator must be the only reader of the receiver. It waits for the first packet
+// to learn the stream format; a later change of the channel count or sample rate ends the
+// stream with an error. DriftCompensator is not safe for concurrent use.
+type DriftCompensator struct {
+	recv *AudioReceiver
+	cfg  DriftCompensatorConfig
+
+	rs       *Resampler
+	format   AudioFormat // Output format
+	inRate   float64     // Sample rate of the received stream (Hz)
+	nominal  float64     // Nominal ratio (stream rate / output rate)
+	level    float64     // Smoothed buffer level error (s)
+	integral float64     // Integrated buffer level error (s*s)
+	last     time.Time   // Time of the last controller update
+}
+
+// NewDriftCompensator creates a compensator reading from recv.
+func NewDriftCompensator(recv *AudioReceiver, cfg DriftCompensatorConfig) (*DriftCompensator, error) {
+	if recv == nil {
+		return nil, errors.New("receiver cannot be nil")
+	}
+	if cfg.MaxCorrection <= 0 {
+		cfg.MaxCorrection = defaultMaxDrift
+	}
+	if _, ok := resamplePresets[cfg.Quality]; !ok {
+		return nil, errors.New("unknown resample quality")
+	}
+	return &DriftCompensator{recv: recv, cfg: cfg}, nil
+}
+
+// Format returns the format of the output stream. ok is false until the first packet of
+// the stream has been received.
+func (c *DriftCompensator) Format() (format AudioFormat, ok bool) {
+	if c.rs == nil {
+		return AudioFormat{}, false
+	}
+	return c.format, true
+}
+
+// Correction returns the current rate correction applied on top of the nominal ratio,
+// e.g. 1.0001 when consuming the stream 100 ppm faster than nominal.
+func (c *DriftCompensator) Correction() float64 {
+	if c.rs == nil {
+		return 1
+	}
+	return c.rs.Ratio() / c.nominal
+}
+
+// ReadFloat32 reads drift-compensated interleaved samples into dst. It blocks until at
+// least one frame is available and returns io.EOF once the receiver is closed.
+func (c *DriftCompensator) ReadFloat32(dst []float32) (int, error) {
+	if c.rs == nil {
+		if err := c.init(); err != nil {
+			return 0, err
+		}
+	}
+	if format, _ := c.recv.Format(); format.Channels != c.format.Channels || format.SampleRate() != uint32(c.inRate) {
+		return 0, errors.New("stream format changed")
+	}
+	c.update(time.Now())
+	return c.rs.ReadFloat32(dst)
+}
+
+// init waits for the stream format and sets up the resampler.
+func (c *DriftCompensator) init() error {
+	format, ok := c.recv.waitFormat()
+	if !ok {
+		return io.EOF
+	}
+	outRate := c.cfg.OutputRate
+	if outRate == 0 {
+		outRate = format.SampleRate()
+	}
+	rs, err := NewResampler(c.recv, format.Channels, format.SampleRate(), outRate, c.cfg.Quality)
+	if err != nil {
+		return err
+	}
+	c.rs = rs
+	c.format = format
+	c.inRate = float64(format.SampleRate())
+	if outRate != format.SampleRate() {
+		if index, err := FindSRIndex(outRate); err == nil {
+			c.format.SRIndex = index
+		}
+	}
+	c.nominal = rs.Ratio()
+	return nil
+}
+
+// update runs one step of the buffer level controller.
+func (c *DriftCompensator) update(now time.Time) {
+	buffered, target := c.recv.BufferLevel()
+	errSec := float64(buffered-target) / c.inRate
+	if c.last.IsZero() {
+		c.level = errSec
+		c.last = now
+		return
+	}
+	dt := now.Sub(c.last).Seconds()
+	if dt < driftMinInterval {
+		return
+	}
+	c.last = now
+	c.level += (errSec - c.level) * (1 - math.Exp(-dt/driftLevelTau))
+
+	// Integrate with anti-windup: stop accumulating once the integral term saturates.
+	limit := c.cfg.MaxCorrection
+	if next := c.integral + c.level*dt; math.Abs(driftKi*next) <= limit {
+		c.integral = next
+	}
+	correction := c.recv.Drift() * (1 + driftKp*c.level + driftKi*c.integral)
+	correction = min(max(correction, 1-limit), 1+limit)
+	c.rs.SetRatio(c.nominal * correction)
+}
@@ -0,0 +1,25 @@
+package vban
+
+import "errors"
+
+// Sentinel errors returned (possibly wrapped) by this package. Use errors.Is to test for them.
+var (
+	// ErrClosed is returned when using a Conn that has been closed. Errors caused by closing
+	// the connection during a pending operation also match net.ErrClosed.
+	ErrClosed = errors.New("connection is closed")
+
+	// ErrBadMagic is returned when a header does not start with the 'VBAN' magic number.
+	ErrBadMagic = errors.New("invalid VBAN magic number")
+
+	// ErrShortPacket is returned when a packet or header is shorter than HeaderSize
+	// (including empty UDP datagrams).
+	ErrShortPacket = errors.New("packet too short")
+
+	// ErrOversized is returned when a packet exceeds MaxVBANPacketSize or a payload
+	// exceeds MaxPacketDataSize.
+	ErrOversized = errors.New("packet exceeds VBAN maximum size")
+
+	// ErrInvalid is matched by the *ValidationError returned when a packet violates the
+	// protocol rules, e.g. by Validate or by a Conn in strict mode.
+	ErrInvalid = errors.New("invalid VBAN packet")
+)
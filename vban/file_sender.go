@@ -0,0 +1,171 @@
+package vban
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// FileSenderConfig configures a FileSender.
+type FileSenderConfig struct {
+	StreamName       string // Name of the outgoing stream (required)
+	Loop             bool   // Restart from the beginning at the end of the file
+	SamplesPerPacket int    // Frames per packet (the largest allowed for the format if zero)
+
+	// OnLoop, if set, is called from Run each time playback wraps around to the start.
+	OnLoop func()
+
+	// OnSendError, if set, is called from Run when a packet cannot be sent (e.g. because
+	// nothing listens at a dialed address), and streaming continues. Otherwise Run returns
+	// the error.
+	OnSendError func(err error)
+}
+
+// FileSender streams an AudioFile as a VBAN audio stream in real time, in the file's
+// native sample format and rate. Packets are as large as the VBAN payload limit allows
+// for the format (see AudioStreamWriter), unless SamplesPerPacket is set, and are paced
+// against the local clock. Seek may be called while Run is sending.
+type FileSender struct {
+	w    *AudioStreamWriter
+	cfg  FileSenderConfig
+	rate float64
+
+	mu   sync.Mutex
+	file *AudioFile
+	buf  []byte
+}
+
+// NewFileSender creates a sender for file. If conn was created using Dial, addr can be nil
+// to send to the dialed address.
+func NewFileSender(conn *Conn, addr *net.UDPAddr, file *AudioFile, cfg FileSenderConfig) (*FileSender, error) {
+	if file == nil {
+		return nil, errors.New("file cannot be nil")
+	}
+	if cfg.StreamName == "" {
+		return nil, errors.New("stream name is required")
+	}
+	format := file.Format()
+	w, err := NewAudioStreamWriter(conn, addr, cfg.StreamName, format)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.SamplesPerPacket != 0 {
+		if err := w.SetSamplesPerPacket(cfg.SamplesPerPacket); err != nil {
+			return nil, err
+		}
+	}
+	return &FileSender{
+		w:    w,
+		cfg:  cfg,
+		rate: float64(format.SampleRate()),
+		file: file,
+		buf:  make([]byte, w.SamplesPerPacket()*file.frameSize),
+	}, nil
+}
+
+// Format returns the format of the outgoing stream.
+func (s *FileSender) Format() AudioFormat { return s.w.Format() }
+
+// SamplesPerPacket returns the number of frames sent in each full packet.
+func (s *FileSender) SamplesPerPacket() int { return s.w.SamplesPerPacket() }
+
+// Duration returns the length of the file.
+func (s *FileSender) Duration() time.Duration {
+	return s.frameTime(s.file.Frames())
+}
+
+// Position returns the playback position in the file.
+func (s *FileSender) Position() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.frameTime(s.file.Position())
+}
+
+// Seek moves the playback position, clamped to the file length.
+func (s *FileSender) Seek(pos time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.SeekFrame(int64(pos.Seconds() * s.rate))
+}
+
+// frameTime converts a frame count to a duration.
+func (s *FileSender) frameTime(frames int64) time.Duration {
+	return time.Duration(float64(frames) / s.rate * float64(time.Second))
+}
+
+// Run sends the file until its end (returning nil), or forever if Loop is set, until ctx
+// is canceled (returning ctx.Err()) or sending fails. Packet k is sent at the time its
+// first frame is due after the start of Run.
+func (s *FileSender) Run(ctx context.Context) error {
+	start := time.Now()
+	var sent int64 // Frames sent since start
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		if wait := time.Until(start.Add(s.frameTime(sent))); wait > 0 {
+			timer.Reset(wait)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-timer.C:
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, looped, err := s.read()
+		if err == io.EOF {
+			err := s.w.Flush()
+			if err != nil && s.cfg.OnSendError != nil {
+				s.cfg.OnSendError(err)
+				return nil
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if looped && s.cfg.OnLoop != nil {
+			s.cfg.OnLoop()
+		}
+		if _, err := s.w.Write(s.buf[:n]); err != nil {
+			if s.cfg.OnSendError == nil {
+				return err
+			}
+			s.cfg.OnSendError(err)
+		}
+		sent += int64(n / s.file.frameSize)
+	}
+}
+
+// read fills s.buf with the next packet's worth of frames, wrapping around at the end of
+// the file when looping.
+func (s *FileSender) read() (n int, looped bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for n < len(s.buf) {
+		k, err := s.file.Read(s.buf[n:])
+		n += k
+		if err == io.EOF {
+			if !s.cfg.Loop || s.file.Frames() == 0 {
+				if n > 0 {
+					return n, looped, nil
+				}
+				return 0, looped, io.EOF
+			}
+			if err := s.file.SeekFrame(0); err != nil {
+				return n, looped, err
+			}
+			looped = true
+			continue
+		}
+		if err != nil {
+			return n, looped, fmt.Errorf("failed to read audio file: %w", err)
+		}
+	}
+	return n, looped, nil
+}
@@ -2,7 +2,6 @@ package vban
 
 import (
 	"bytes"
-	"encoding/binary"
 	"errors"
 	"fmt"
 )
@@ -127,75 +126,39 @@ func (h *Header) SetAudioFormat(srIndex SRIndex, dataType DataType, codec CodecT
 
 // MarshalBinary converts the Header struct into its 28-byte representation (Little Endian).
 func (h *Header) MarshalBinary() ([]byte, error) {
-	buf := new(bytes.Buffer)
-	buf.Grow(HeaderSize)
+	return h.AppendBinary(make([]byte, 0, HeaderSize))
+}
 
+// AppendBinary appends the 28-byte representation (Little Endian) of the Header to b.
+// It does not allocate if b has enough spare capacity.
+func (h *Header) AppendBinary(b []byte) ([]byte, error) {
 	// Write fields in the exact order defined by the VBAN specification.
-	if err := binary.Write(buf, byteOrder, h.VBAN); err != nil {
-		return nil, fmt.Errorf("failed to write VBAN magic: %w", err)
-	}
-	if err := binary.Write(buf, byteOrder, h.FormatSR); err != nil {
-		return nil, fmt.Errorf("failed to write FormatSR: %w", err)
-	}
-	if err := binary.Write(buf, byteOrder, h.FormatNbs); err != nil {
-		return nil, fmt.Errorf("failed to write FormatNbs: %w", err)
-	}
-	if err := binary.Write(buf, byteOrder, h.FormatNbc); err != nil {
-		return nil, fmt.Errorf("failed to write FormatNbc: %w", err)
-	}
-	if err := binary.Write(buf, byteOrder, h.FormatBit); err != nil {
-		return nil, fmt.Errorf("failed to write FormatBit: %w", err)
-	}
-	if _, err := buf.Write(h.StreamName[:]); err != nil { // Write StreamName bytes directly
-		return nil, fmt.Errorf("failed to write StreamName: %w", err)
-	}
-	if err := binary.Write(buf, byteOrder, h.NuFrame); err != nil {
-		return nil, fmt.Errorf("failed to write NuFrame: %w", err)
-	}
-
-	// Final check of buffer length
-	if buf.Len() != HeaderSize {
-		return nil, fmt.Errorf("internal error: marshaled header size is %d, expected %d", buf.Len(), HeaderSize)
-	}
-	return buf.Bytes(), nil
+	b = byteOrder.AppendUint32(b, h.VBAN)
+	b = append(b, h.FormatSR, h.FormatNbs, h.FormatNbc, h.FormatBit)
+	b = append(b, h.StreamName[:]...)
+	b = byteOrder.AppendUint32(b, h.NuFrame)
+	return b, nil
 }
 
 // UnmarshalBinary parses a 28-byte slice (Little Endian) into the Header struct.
-// It performs basic validation of the VBAN magic number.
+// It performs basic validation of the VBAN magic number and leaves the Header
+// unchanged on error. It does not allocate unless an error is returned.
 func (h *Header) UnmarshalBinary(data []byte) error {
 	if len(data) < HeaderSize {
-		return fmt.Errorf("insufficient data for header: expected %d bytes, got %d", HeaderSize, len(data))
-	}
-	// Use a reader for structured reading
-	buf := bytes.NewReader(data[:HeaderSize]) // Ensure reading only header size
-
-	// Read fields in the exact order defined by the VBAN specification.
-	if err := binary.Read(buf, byteOrder, &h.VBAN); err != nil {
-		return fmt.Errorf("failed to read VBAN magic: %w", err)
+		return fmt.Errorf("%w: insufficient data for header: expected %d bytes, got %d", ErrShortPacket, HeaderSize, len(data))
 	}
 	// Validate Magic Number immediately
-	if h.VBAN != HeaderMagic {
-		return fmt.Errorf("invalid VBAN magic number: expected %X, got %X", HeaderMagic, h.VBAN)
-	}
-
-	if err := binary.Read(buf, byteOrder, &h.FormatSR); err != nil {
-		return fmt.Errorf("failed to read FormatSR: %w", err)
-	}
-	if err := binary.Read(buf, byteOrder, &h.FormatNbs); err != nil {
-		return fmt.Errorf("failed to read FormatNbs: %w", err)
-	}
-	if err := binary.Read(buf, byteOrder, &h.FormatNbc); err != nil {
-		return fmt.Errorf("failed to read FormatNbc: %w", err)
-	}
-	if err := binary.Read(buf, byteOrder, &h.FormatBit); err != nil {
-		return fmt.Errorf("failed to read FormatBit: %w", err)
-	}
-	if _, err := buf.Read(h.StreamName[:]); err != nil { // Read directly into StreamName byte array
-		return fmt.Errorf("failed to read StreamName: %w", err)
-	}
-	if err := binary.Read(buf, byteOrder, &h.NuFrame); err != nil {
-		return fmt.Errorf("failed to read NuFrame: %w", err)
+	if magic := byteOrder.Uint32(data[0:4]); magic != HeaderMagic {
+		return fmt.Errorf("%w: expected %X, got %X", ErrBadMagic, HeaderMagic, magic)
 	}
 
+	// Read fields in the exact order defined by the VBAN specification.
+	h.VBAN = HeaderMagic
+	h.FormatSR = data[4]
+	h.FormatNbs = data[5]
+	h.FormatNbc = data[6]
+	h.FormatBit = data[7]
+	copy(h.StreamName[:], data[8:8+MaxStreamNameLen])
+	h.NuFrame = byteOrder.Uint32(data[8+MaxStreamNameLen : HeaderSize])
 	return nil
 }
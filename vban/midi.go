@@ -0,0 +1,276 @@
+package vban
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// MaxSysExLen limits the size of a System Exclusive message assembled by MIDIParser.
+// Longer messages are discarded to protect against unterminated SysEx streams.
+const MaxSysExLen = 64 * 1024
+
+// DefaultMIDIBPSIndex is the BPS index of the standard MIDI baud rate (31250 bps).
+const DefaultMIDIBPSIndex SRIndex = 11
+
+// MIDIMessage is a single complete MIDI message, starting with its status byte.
+// Messages produced by MIDIParser always carry an explicit status byte, even when
+// the sender used running status.
+type MIDIMessage []byte
+
+// Status returns the status byte of the message, or 0 if the message is empty.
+func (m MIDIMessage) Status() byte {
+	if len(m) == 0 {
+		return 0
+	}
+	return m[0]
+}
+
+// Channel returns the MIDI channel (0-15) of a channel message, or -1 for system messages.
+func (m MIDIMessage) Channel() int {
+	s := m.Status()
+	if s < 0x80 || s >= 0xF0 {
+		return -1
+	}
+	return int(s & 0x0F)
+}
+
+// IsSysEx reports whether the message is a System Exclusive message.
+func (m MIDIMessage) IsSysEx() bool { return m.Status() == 0xF0 }
+
+// IsRealtime reports whether the message is a single-byte System Real-Time message.
+func (m MIDIMessage) IsRealtime() bool { return m.Status() >= 0xF8 }
+
+// midiDataLen returns the number of data bytes following the given status byte,
+// or -1 for System Exclusive.
+func midiDataLen(status byte) int {
+	switch {
+	case status < 0xC0: // Note Off, Note On, Poly Pressure, Control Change
+		return 2
+	case status < 0xE0: // Program Change, Channel Pressure
+		return 1
+	case status < 0xF0: // Pitch Bend
+		return 2
+	}
+	switch status {
+	case 0xF0:
+		return -1
+	case 0xF1, 0xF3: // MTC Quarter Frame, Song Select
+		return 1
+	case 0xF2: // Song Position Pointer
+		return 2
+	default: // Tune Request, EOX, Real-Time and undefined messages
+		return 0
+	}
+}
+
+// MIDIParser splits a MIDI byte stream into complete messages. It keeps state across
+// calls to Parse, so messages (including SysEx) spanning several packets are reassembled,
+// and running status is expanded. Use one parser per stream and source.
+// The zero value is ready to use.
+type MIDIParser struct {
+	running byte   // Current running status (0 if none)
+	msg     []byte // Message being assembled
+	need    int    // Data bytes still missing from msg
+	sysex   bool   // A SysEx message is being assembled
+}
+
+// Reset discards any partially assembled message and the running status.
+func (p *MIDIParser) Reset() {
+	*p = MIDIParser{}
+}
+
+// Parse consumes data and returns the messages completed by it. Stray data bytes
+// without a status, and SysEx messages interrupted by another status byte, are dropped.
+func (p *MIDIParser) Parse(data []byte) []MIDIMessage {
+	var msgs []MIDIMessage
+	for _, b := range data {
+		switch {
+		case b >= 0xF8:
+			// Real-Time messages may appear anywhere and do not affect the parser state.
+			msgs = append(msgs, MIDIMessage{b})
+		case b == 0xF7:
+			if p.sysex {
+				msgs = append(msgs, MIDIMessage(append(p.msg, b)))
+				p.msg, p.sysex = nil, false
+			}
+		case b >= 0x80:
+			p.sysex = false
+			p.msg = append(make([]byte, 0, 3), b)
+			p.need = midiDataLen(b)
+			switch {
+			case b < 0xF0:
+				p.running = b
+			case b == 0xF0:
+				p.running = 0
+				p.sysex = true
+				p.need = 0
+			default:
+				p.running = 0 // System Common messages cancel running status
+			}
+			if !p.sysex && p.need == 0 {
+				msgs = append(msgs, MIDIMessage(p.msg))
+				p.msg = nil
+			}
+		case p.sysex:
+			if len(p.msg) >= MaxSysExLen {
+				p.msg, p.sysex = nil, false // Unterminated or oversized SysEx
+				continue
+			}
+			p.msg = append(p.msg, b)
+		case p.msg == nil && p.running != 0:
+			// Running status: the data byte starts a new message with the previous status.
+			p.msg = append(make([]byte, 0, 3), p.running, b)
+			p.need = midiDataLen(p.running) - 1
+			if p.need == 0 {
+				msgs = append(msgs, MIDIMessage(p.msg))
+				p.msg = nil
+			}
+		case p.msg != nil && p.need > 0:
+			p.msg = append(p.msg, b)
+			p.need--
+			if p.need == 0 {
+				msgs = append(msgs, MIDIMessage(p.msg))
+				p.msg = nil
+			}
+		}
+	}
+	return msgs
+}
+
+// MIDIMessages parses the payload of a single self-contained MIDI packet.
+// Use a MIDIParser to reassemble messages spanning several packets.
+func (p *Packet) MIDIMessages() ([]MIDIMessage, error) {
+	if !p.Header.SubProtocol().IsSerial() || p.Header.CodecType() != SerialMIDI {
+		return nil, errors.New("packet is not a MIDI packet")
+	}
+	var parser MIDIParser
+	return parser.Parse(p.Data), nil
+}
+
+// EncodeMIDIPackets packs MIDI messages into as few Serial packets as possible, using header
+// as a template (see SetSerialFormat). Messages are not split across packets unless they are
+// larger than MaxPacketDataSize; a packet whose last message continues in the next packet has
+// SerialMultipart set. The first packet uses header.NuFrame, and each subsequent packet
+// increments it.
+func EncodeMIDIPackets(header Header, msgs []MIDIMessage) ([]*Packet, error) {
+	var packets []*Packet
+	var data []byte
+	multipart := false
+	flush := func() {
+		h := header
+		h.NuFrame = header.NuFrame + uint32(len(packets))
+		mode := h.SerialBitMode() &^ SerialMultipart
+		if multipart {
+			mode |= SerialMultipart
+		}
+		h.SetSerialBitMode(mode)
+		packets = append(packets, &Packet{Header: h, Data: data})
+		data, multipart = nil, false
+	}
+	for i, msg := range msgs {
+		if len(msg) == 0 || msg[0] < 0x80 {
+			return nil, fmt.Errorf("MIDI message %d does not start with a status byte", i)
+		}
+		if len(data) == MaxPacketDataSize || len(data)+len(msg) > MaxPacketDataSize && len(msg) <= MaxPacketDataSize {
+			flush() // Full, or the message fits whole in a new packet
+		}
+		for len(msg) > 0 {
+			k := min(MaxPacketDataSize-len(data), len(msg))
+			data = append(data, msg[:k]...)
+			msg = msg[k:]
+			if len(msg) > 0 {
+				multipart = true
+				flush()
+			}
+		}
+	}
+	if len(data) > 0 {
+		flush()
+	}
+	return packets, nil
+}
+
+// MIDIWriter sends MIDI messages as a VBAN Serial stream. It is not safe for concurrent use.
+type MIDIWriter struct {
+	conn   *Conn
+	addr   *net.UDPAddr
+	header Header
+}
+
+// NewMIDIWriter creates a writer for the MIDI stream streamName, announcing the standard
+// MIDI bit rate (31250 bps, 8N1). If conn was created using Dial, addr can be nil.
+func NewMIDIWriter(conn *Conn, addr *net.UDPAddr, streamName string) (*MIDIWriter, error) {
+	if conn == nil {
+		return nil, errors.New("connection cannot be nil")
+	}
+	h := NewHeader(ProtocolSerial, streamName)
+	h.SetSerialFormat(DefaultMIDIBPSIndex, DataTypeUINT8, SerialMIDI)
+	h.SetSerialBitMode(SerialStartBit | SerialStopBits1)
+	return &MIDIWriter{conn: conn, addr: addr, header: h}, nil
+}
+
+// Header returns a pointer to the template header, allowing fields such as the
+// channel identifier to be adjusted before sending.
+func (w *MIDIWriter) Header() *Header { return &w.header }
+
+// WriteMessages sends the messages in as few packets as possible.
+func (w *MIDIWriter) WriteMessages(msgs ...MIDIMessage) error {
+	packets, err := EncodeMIDIPackets(w.header, msgs)
+	if err != nil {
+		return err
+	}
+	for _, packet := range packets {
+		if err := w.conn.Send(packet, w.addr); err != nil {
+			return fmt.Errorf("failed to send MIDI packet %d: %w", packet.Header.NuFrame, err)
+		}
+		w.header.NuFrame++
+	}
+	return nil
+}
+
+// midiStreamKey identifies a MIDI stream for per-stream parser state.
+type midiStreamKey struct {
+	streamName string
+	source     string
+}
+
+// MIDIHandler is a Handler that reassembles MIDI messages from incoming Serial packets
+// and passes each complete message to a callback. It keeps separate parser state for
+// every stream name and source address. It is safe for concurrent use.
+type MIDIHandler struct {
+	mu      sync.Mutex
+	parsers map[midiStreamKey]*MIDIParser
+	fn      func(msg MIDIMessage, h *Header, addr *net.UDPAddr)
+}
+
+// NewMIDIHandler returns a MIDIHandler calling fn for every complete message.
+func NewMIDIHandler(fn func(msg MIDIMessage, h *Header, addr *net.UDPAddr)) *MIDIHandler {
+	return &MIDIHandler{
+		parsers: make(map[midiStreamKey]*MIDIParser),
+		fn:      fn,
+	}
+}
+
+// HandlePacket parses a Serial MIDI packet. Other packets are ignored.
+func (m *MIDIHandler) HandlePacket(p *Packet, addr *net.UDPAddr) {
+	if p == nil || !p.Header.SubProtocol().IsSerial() || p.Header.CodecType() != SerialMIDI {
+		return
+	}
+	key := midiStreamKey{streamName: p.Header.GetStreamName()}
+	if addr != nil {
+		key.source = addr.String()
+	}
+	m.mu.Lock()
+	parser, ok := m.parsers[key]
+	if !ok {
+		parser = &MIDIParser{}
+		m.parsers[key] = parser
+	}
+	msgs := parser.Parse(p.Data)
+	m.mu.Unlock()
+	for _, msg := range msgs {
+		m.fn(msg, &p.Header, addr)
+	}
+}
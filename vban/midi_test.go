@@ -0,0 +1,56 @@
+package vban
+
+import (
+	"bytes"
+	"testing"
+)
+
+// sysEx returns a System Exclusive message of n bytes.
+func sysEx(n int) MIDIMessage {
+	msg := make(MIDIMessage, n)
+	msg[0] = 0xF0
+	msg[n-1] = 0xF7
+	return msg
+}
+
+func TestEncodeMIDIPacketsFullPacketBeforeOversized(t *testing.T) {
+	h := NewHeader(ProtocolSerial, "MIDI")
+	h.SetSerialFormat(DefaultMIDIBPSIndex, DataTypeUINT8, SerialMIDI)
+	full := sysEx(MaxPacketDataSize)
+	large := sysEx(MaxPacketDataSize + 10)
+
+	packets, err := EncodeMIDIPackets(h, []MIDIMessage{full, large})
+	if err != nil {
+		t.Fatalf("EncodeMIDIPackets: %v", err)
+	}
+	want := []struct {
+		size      int
+		multipart bool
+	}{
+		{MaxPacketDataSize, false}, // Complete message only
+		{MaxPacketDataSize, true},  // Start of the oversized message
+		{10, false},                // Its end
+	}
+	if len(packets) != len(want) {
+		t.Fatalf("got %d packets, want %d", len(packets), len(want))
+	}
+	for i, w := range want {
+		p := packets[i]
+		if len(p.Data) != w.size || p.Header.SerialBitMode().IsMultipart() != w.multipart {
+			t.Errorf("packet %d: got %d bytes, multipart %v; want %d bytes, multipart %v",
+				i, len(p.Data), p.Header.SerialBitMode().IsMultipart(), w.size, w.multipart)
+		}
+		if p.Header.NuFrame != uint32(i) {
+			t.Errorf("packet %d: NuFrame %d", i, p.Header.NuFrame)
+		}
+	}
+
+	var parser MIDIParser
+	var msgs []MIDIMessage
+	for _, p := range packets {
+		msgs = append(msgs, parser.Parse(p.Data)...)
+	}
+	if len(msgs) != 2 || !bytes.Equal(msgs[0], full) || !bytes.Equal(msgs[1], large) {
+		t.Errorf("reassembled %d messages, want the 2 sent", len(msgs))
+	}
+}
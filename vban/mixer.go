@@ -0,0 +1,386 @@
+package vban
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// DefaultMixerBuffer is the per-input buffering used when MixerConfig.Buffer is zero.
+const DefaultMixerBuffer = 4 // Blocks
+
+// MixerConfig configures a Mixer.
+type MixerConfig struct {
+	BlockFrames int // Frames mixed per block (the writer's SamplesPerPacket if zero)
+	Buffer      int // Blocks buffered per input before the oldest samples are dropped (DefaultMixerBuffer if zero)
+}
+
+// Mixer combines several audio streams into one outgoing VBAN stream, like a
+// Voicemeeter bus. Each input has its own gain, mute, pan and input-to-output channel
+// routing matrix. Inputs are Float32Readers (for example AudioReceivers or Resamplers)
+// that must run at the output sample rate; their channel counts and original data types
+// may differ. The mix is clipped to [-1, 1] and sent through an AudioStreamWriter, which
+// encodes it in the output DataType.
+//
+// Each input is read by its own goroutine into a small buffer, and Run mixes one block at
+// the output rate from whatever each input has buffered, so a stalled input is replaced by
+// silence instead of blocking the other inputs. The mixer owns the sources added to it:
+// RemoveInput and Close close those that implement io.Closer (such as AudioReceiver),
+// which ends their reader goroutines. Sources that do not, such as a Resampler, must be
+// stopped by the caller, e.g. by closing the receiver they read from. Mixer is safe for
+// concurrent use, except that the writer must not be used by anything else while the
+// mixer runs.
+type Mixer struct {
+	w      *AudioStreamWriter
+	cfg    MixerConfig
+	outCh  int
+	period time.Duration // Duration of one block at the output rate
+
+	mu     sync.Mutex
+	inputs []*MixerInput
+	mix    []float32 // Mixing buffer for one block
+	closed bool
+
+	sendMu sync.Mutex // Serializes MixBlock's writes; acquired before mu
+	send   []float32  // Copy of the clipped mix being written, guarded by sendMu
+}
+
+// NewMixer creates a mixer sending its output through w.
+func NewMixer(w *AudioStreamWriter, cfg MixerConfig) (*Mixer, error) {
+	if w == nil {
+		return nil, errors.New("writer cannot be nil")
+	}
+	if cfg.BlockFrames <= 0 {
+		cfg.BlockFrames = w.SamplesPerPacket()
+	}
+	if cfg.Buffer <= 0 {
+		cfg.Buffer = DefaultMixerBuffer
+	}
+	format := w.Format()
+	return &Mixer{
+		w:      w,
+		cfg:    cfg,
+		outCh:  format.Channels,
+		period: time.Duration(float64(cfg.BlockFrames) / float64(format.SampleRate()) * float64(time.Second)),
+		mix:    make([]float32, cfg.BlockFrames*format.Channels),
+		send:   make([]float32, cfg.BlockFrames*format.Channels),
+	}, nil
+}
+
+// MixerInput is one input of a Mixer. Its settings can be changed at any time.
+type MixerInput struct {
+	m        *Mixer
+	src      Float32Reader
+	channels int
+
+	// The following fields are guarded by m.mu.
+	gain   float32
+	mute   bool
+	pan    float32
+	routes [][]float32 // Routing gains indexed by [input channel][output channel]
+	fifo   []float32   // Buffered interleaved samples
+	err    error       // Error that ended the input's reader
+}
+
+// AddInput adds a source with the given number of interleaved channels and starts reading
+// from it. By default a mono input is routed to all output channels, and input channel i
+// of a multichannel input is routed to output channel i (channels beyond the output's
+// count are dropped), at unity gain. The input is removed automatically once src returns
+// an error (such as io.EOF) and its buffered samples have been mixed.
+//
+// If src reports its format (like AudioReceiver and DriftCompensator, from the channel
+// count in the stream's headers) or its channel count (like Resampler), channels must
+// match it. A source whose format is not known yet is checked as it is read; if its
+// channel count differs, the input fails with an error instead of scrambling the routing.
+func (m *Mixer) AddInput(src Float32Reader, channels int) (*MixerInput, error) {
+	if src == nil {
+		return nil, errors.New("source cannot be nil")
+	}
+	if channels < 1 {
+		return nil, fmt.Errorf("invalid channel count: %d", channels)
+	}
+	if err := checkSourceChannels(src, channels); err != nil {
+		return nil, err
+	}
+	in := &MixerInput{m: m, src: src, channels: channels, gain: 1}
+	in.routes = make([][]float32, channels)
+	for i := range in.routes {
+		in.routes[i] = make([]float32, m.outCh)
+		switch {
+		case channels == 1:
+			for o := range in.routes[i] {
+				in.routes[i][o] = 1
+			}
+		case i < m.outCh:
+			in.routes[i][i] = 1
+		}
+	}
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, ErrClosed
+	}
+	m.inputs = append(m.inputs, in)
+	m.mu.Unlock()
+	go in.read(src)
+	return in, nil
+}
+
+// checkSourceChannels returns an error if src reports a channel count other than channels.
+func checkSourceChannels(src Float32Reader, channels int) error {
+	var n int
+	switch s := src.(type) {
+	case interface{ Format() (AudioFormat, bool) }:
+		format, ok := s.Format()
+		if !ok {
+			return nil // Not known yet
+		}
+		n = format.Channels
+	case interface{ Channels() int }:
+		n = s.Channels()
+	default:
+		return nil
+	}
+	if n != channels {
+		return fmt.Errorf("input has %d channels, but the source has %d", channels, n)
+	}
+	return nil
+}
+
+// read copies samples from src into the input's buffer until src fails or the input is
+// removed.
+func (in *MixerInput) read(src Float32Reader) {
+	m := in.m
+	buf := make([]float32, m.cfg.BlockFrames*in.channels)
+	limit := m.cfg.Buffer * len(buf)
+	for {
+		n, err := src.ReadFloat32(buf)
+		if n > 0 {
+			if cerr := checkSourceChannels(src, in.channels); cerr != nil {
+				n, err = 0, cerr
+			}
+		}
+		m.mu.Lock()
+		if !m.hasInput(in) {
+			m.mu.Unlock()
+			return
+		}
+		if n == 0 && err == nil {
+			// Nothing available yet: wait a block instead of spinning on the source.
+			m.mu.Unlock()
+			time.Sleep(m.period)
+			continue
+		}
+		in.fifo = append(in.fifo, buf[:n]...)
+		if over := len(in.fifo) - limit; over > 0 {
+			// The output is not keeping up: drop the oldest whole frames.
+			over += (in.channels - over%in.channels) % in.channels
+			in.fifo = in.fifo[:copy(in.fifo, in.fifo[over:])]
+		}
+		if err != nil {
+			in.err = err
+			m.mu.Unlock()
+			return
+		}
+		m.mu.Unlock()
+	}
+}
+
+// hasInput reports whether in is still part of the mix. It must be called with m.mu held.
+func (m *Mixer) hasInput(in *MixerInput) bool {
+	for _, x := range m.inputs {
+		if x == in {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveInput removes an input from the mix and closes its source if it implements
+// io.Closer. Its reader goroutine exits once the pending read of the source returns.
+func (m *Mixer) RemoveInput(in *MixerInput) {
+	m.mu.Lock()
+	removed := m.hasInput(in)
+	m.remove(in)
+	m.mu.Unlock()
+	if removed {
+		in.close()
+	}
+}
+
+// close closes the input's source if it implements io.Closer.
+func (in *MixerInput) close() {
+	if c, ok := in.src.(io.Closer); ok {
+		c.Close()
+	}
+}
+
+// Close removes all inputs, closing their sources, and stops the mixer: Run returns nil
+// and AddInput fails with ErrClosed.
+func (m *Mixer) Close() error {
+	m.mu.Lock()
+	inputs := m.inputs
+	m.inputs = nil
+	m.closed = true
+	m.mu.Unlock()
+	for _, in := range inputs {
+		in.close()
+	}
+	return nil
+}
+
+// remove removes in from the inputs. It must be called with m.mu held.
+func (m *Mixer) remove(in *MixerInput) {
+	for i, x := range m.inputs {
+		if x == in {
+			m.inputs = append(m.inputs[:i], m.inputs[i+1:]...)
+			return
+		}
+	}
+}
+
+// Inputs returns the number of inputs currently mixed.
+func (m *Mixer) Inputs() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.inputs)
+}
+
+// Channels returns the number of interleaved channels of the input.
+func (in *MixerInput) Channels() int { return in.channels }
+
+// SetGain sets the linear gain of the input (1 is unity).
+func (in *MixerInput) SetGain(gain float32) {
+	in.m.mu.Lock()
+	defer in.m.mu.Unlock()
+	in.gain = gain
+}
+
+// SetGainDB sets the gain of the input in decibels (0 is unity).
+func (in *MixerInput) SetGainDB(db float64) {
+	in.SetGain(float32(math.Pow(10, db/20)))
+}
+
+// SetMute mutes or unmutes the input.
+func (in *MixerInput) SetMute(mute bool) {
+	in.m.mu.Lock()
+	defer in.m.mu.Unlock()
+	in.mute = mute
+}
+
+// SetPan sets the balance of the input between -1 (left) and 1 (right). Even output
+// channels are treated as left and odd ones as right; at 0 both are at unity gain, and
+// the opposite side is attenuated linearly down to silence at the extremes. Pan has no
+// effect on a mono output.
+func (in *MixerInput) SetPan(pan float32) {
+	in.m.mu.Lock()
+	defer in.m.mu.Unlock()
+	in.pan = min(max(pan, -1), 1)
+}
+
+// SetRoute sets the gain from input channel inCh to output channel outCh in the routing
+// matrix (0 disconnects them).
+func (in *MixerInput) SetRoute(inCh, outCh int, gain float32) error {
+	if inCh < 0 || inCh >= in.channels || outCh < 0 || outCh >= in.m.outCh {
+		return fmt.Errorf("invalid route: input channel %d to output channel %d", inCh, outCh)
+	}
+	in.m.mu.Lock()
+	defer in.m.mu.Unlock()
+	in.routes[inCh][outCh] = gain
+	return nil
+}
+
+// ClearRoutes disconnects all input channels from all output channels.
+func (in *MixerInput) ClearRoutes() {
+	in.m.mu.Lock()
+	defer in.m.mu.Unlock()
+	for _, row := range in.routes {
+		clear(row)
+	}
+}
+
+// Err returns the error that ended reading from the input's source, or nil.
+func (in *MixerInput) Err() error {
+	in.m.mu.Lock()
+	defer in.m.mu.Unlock()
+	return in.err
+}
+
+// panGain returns the balance gain applied to output channel o. It must be called with
+// m.mu held.
+func (in *MixerInput) panGain(o int) float32 {
+	if in.m.outCh < 2 || in.pan == 0 {
+		return 1
+	}
+	if o%2 == 0 {
+		return min(1, 1-in.pan)
+	}
+	return min(1, 1+in.pan)
+}
+
+// MixBlock mixes one block of BlockFrames frames from the samples currently buffered by
+// each input (padding inputs that have not buffered enough with silence) and sends it.
+// The inputs can be changed while the block is being sent. It returns ErrClosed once the
+// mixer is closed.
+func (m *Mixer) MixBlock() error {
+	m.sendMu.Lock()
+	defer m.sendMu.Unlock()
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return ErrClosed
+	}
+	clear(m.mix)
+	for i := 0; i < len(m.inputs); i++ {
+		in := m.inputs[i]
+		frames := min(m.cfg.BlockFrames, len(in.fifo)/in.channels)
+		if !in.mute && in.gain != 0 {
+			for c, row := range in.routes {
+				for o, route := range row {
+					g := route * in.gain * in.panGain(o)
+					if g == 0 {
+						continue
+					}
+					for f := range frames {
+						m.mix[f*m.outCh+o] += g * in.fifo[f*in.channels+c]
+					}
+				}
+			}
+		}
+		in.fifo = in.fifo[:copy(in.fifo, in.fifo[frames*in.channels:])]
+		if in.err != nil && len(in.fifo) < in.channels {
+			m.remove(in) // Source ended and fully mixed
+			i--
+		}
+	}
+	for i, v := range m.mix {
+		m.send[i] = min(max(v, -1), 1)
+	}
+	m.mu.Unlock()
+	return m.w.WriteFloat32(m.send)
+}
+
+// Run mixes and sends one block per block duration at the output sample rate until ctx
+// is canceled (returning ctx.Err()), the mixer is closed (returning nil) or sending fails.
+func (m *Mixer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := m.MixBlock(); err != nil {
+				if errors.Is(err, ErrClosed) {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+}
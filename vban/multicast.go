@@ -0,0 +1,133 @@
+package vban
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// --- Multicast ---
+
+// ListenMulticast creates a VBAN Conn that receives packets sent to the multicast group
+// address (IPv4 or IPv6) on the given interface. If iface is nil, the system-assigned
+// multicast interface is used, which is not recommended for IPv6. If group.Port is 0,
+// DefaultPort is used. Options such as WithReadBuffer tune the socket.
+//
+// The socket is bound to the wildcard address with the port shared (SO_REUSEADDR), so
+// several programs can receive the same group, and unicast packets sent to the port are
+// also received. Additional groups can be joined with JoinGroup. Multicast loopback is
+// disabled; enable it with SetMulticastLoopback to receive packets sent from this host.
+func ListenMulticast(iface *net.Interface, group *net.UDPAddr, opts ...Option) (*Conn, error) {
+	if group == nil || !group.IP.IsMulticast() {
+		return nil, fmt.Errorf("%v is not a multicast group address", group)
+	}
+	addr := *group
+	if addr.Port == 0 {
+		addr.Port = DefaultPort
+	}
+	network := "udp6"
+	if addr.IP.To4() != nil {
+		network = "udp4"
+	}
+	// Listening on a multicast address binds the wildcard address with a shared port.
+	udpConn, err := listenUDP(network, &addr, newOptions(opts))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on UDP %s: %w", addr.String(), err)
+	}
+	conn := NewConn(udpConn)
+	if err := conn.JoinGroup(iface, addr.IP); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if iface != nil {
+		if err := conn.SetMulticastInterface(iface); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if err := conn.SetMulticastLoopback(false); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// JoinGroup joins the multicast group on the given interface (or the system-assigned
+// interface if iface is nil), so that packets sent to the group and the Conn's port are
+// received.
+func (c *Conn) JoinGroup(iface *net.Interface, group net.IP) error {
+	return c.setMulticast(group, "join multicast group",
+		func(p *ipv4.PacketConn) error { return p.JoinGroup(iface, &net.UDPAddr{IP: group}) },
+		func(p *ipv6.PacketConn) error { return p.JoinGroup(iface, &net.UDPAddr{IP: group}) })
+}
+
+// LeaveGroup leaves a multicast group joined with JoinGroup or ListenMulticast.
+func (c *Conn) LeaveGroup(iface *net.Interface, group net.IP) error {
+	return c.setMulticast(group, "leave multicast group",
+		func(p *ipv4.PacketConn) error { return p.LeaveGroup(iface, &net.UDPAddr{IP: group}) },
+		func(p *ipv6.PacketConn) error { return p.LeaveGroup(iface, &net.UDPAddr{IP: group}) })
+}
+
+// SetMulticastTTL sets the time-to-live (IPv4) or hop limit (IPv6) of multicast packets
+// sent through the Conn. The system default is 1, which keeps packets on the local subnet;
+// increase it to reach receivers behind multicast routers.
+func (c *Conn) SetMulticastTTL(ttl int) error {
+	if ttl < 0 || ttl > 255 {
+		return fmt.Errorf("multicast TTL must be between 0 and 255, got %d", ttl)
+	}
+	return c.setMulticast(nil, "set multicast TTL",
+		func(p *ipv4.PacketConn) error { return p.SetMulticastTTL(ttl) },
+		func(p *ipv6.PacketConn) error { return p.SetMulticastHopLimit(ttl) })
+}
+
+// SetMulticastLoopback sets whether multicast packets sent through the Conn are also
+// delivered to receivers on this host.
+func (c *Conn) SetMulticastLoopback(on bool) error {
+	return c.setMulticast(nil, "set multicast loopback",
+		func(p *ipv4.PacketConn) error { return p.SetMulticastLoopback(on) },
+		func(p *ipv6.PacketConn) error { return p.SetMulticastLoopback(on) })
+}
+
+// SetMulticastInterface sets the interface multicast packets are sent from. If iface is
+// nil, the system chooses the interface from the routing table.
+func (c *Conn) SetMulticastInterface(iface *net.Interface) error {
+	return c.setMulticast(nil, "set multicast interface",
+		func(p *ipv4.PacketConn) error { return p.SetMulticastInterface(iface) },
+		func(p *ipv6.PacketConn) error { return p.SetMulticastInterface(iface) })
+}
+
+// setMulticast applies a multicast socket option through the IPv4 or IPv6 API, depending
+// on the address family of ip, the dialed address or the local address, in that order. On
+// a dual-stack socket bound to the unspecified IPv6 address, the option is set for both
+// families and only fails if neither accepts it.
+func (c *Conn) setMulticast(ip net.IP, op string, set4 func(*ipv4.PacketConn) error, set6 func(*ipv6.PacketConn) error) error {
+	udpConn, err := c.conn()
+	if err != nil {
+		return err
+	}
+	if ip == nil {
+		if ra, ok := udpConn.RemoteAddr().(*net.UDPAddr); ok {
+			ip = ra.IP
+		} else if la, ok := udpConn.LocalAddr().(*net.UDPAddr); ok {
+			ip = la.IP
+		}
+	}
+	switch {
+	case ip.To4() != nil:
+		err = set4(ipv4.NewPacketConn(udpConn))
+	case ip.IsUnspecified():
+		err6 := set6(ipv6.NewPacketConn(udpConn))
+		if err4 := set4(ipv4.NewPacketConn(udpConn)); err4 != nil && err6 != nil {
+			err = errors.Join(err4, err6)
+		}
+	default:
+		err = set6(ipv6.NewPacketConn(udpConn))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", op, err)
+	}
+	return nil
+}
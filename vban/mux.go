@@ -0,0 +1,183 @@
+package vban
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+// Handler processes VBAN packets received from the network.
+// Handlers may retain the packet; Conn.Receive allocates a new Packet for every call.
+type Handler interface {
+	HandlePacket(p *Packet, addr *net.UDPAddr)
+}
+
+// HandlerFunc adapts an ordinary function to the Handler interface.
+type HandlerFunc func(p *Packet, addr *net.UDPAddr)
+
+// HandlePacket calls f(p, addr).
+func (f HandlerFunc) HandlePacket(p *Packet, addr *net.UDPAddr) { f(p, addr) }
+
+// muxEntry is a single registration in a Mux.
+type muxEntry struct {
+	subProto   SubProtocol
+	streamName string // Empty matches any stream name
+	source     net.IP // Nil matches any source
+	handler    Handler
+}
+
+// specificity ranks an entry for dispatch: a stream name match beats a source match,
+// and both beat wildcards.
+func (e *muxEntry) specificity() int {
+	s := 0
+	if e.streamName != "" {
+		s += 2
+	}
+	if e.source != nil {
+		s++
+	}
+	return s
+}
+
+// matches reports whether the entry accepts a packet with the given header and source.
+func (e *muxEntry) matches(h *Header, addr *net.UDPAddr) bool {
+	if h.SubProtocol() != e.subProto {
+		return false
+	}
+	if e.streamName != "" && h.GetStreamName() != e.streamName {
+		return false
+	}
+	if e.source != nil && (addr == nil || !e.source.Equal(addr.IP)) {
+		return false
+	}
+	return true
+}
+
+// Mux is a VBAN packet multiplexer, similar to http.ServeMux. It dispatches each
+// packet to the most specific handler registered for the packet's sub-protocol,
+// stream name and source IP. Packets without a matching handler go to the handler
+// set by HandleUnmatched, if any.
+//
+// A stream-specific registration takes precedence over a source-specific one, which
+// takes precedence over a registration matching any stream from any source.
+// Mux itself implements Handler, so muxes can be nested. It is safe for concurrent use.
+type Mux struct {
+	mu        sync.RWMutex
+	entries   []muxEntry
+	unmatched Handler
+}
+
+// NewMux allocates and returns a new Mux.
+func NewMux() *Mux {
+	return &Mux{}
+}
+
+// Handle registers the handler for packets of the given sub-protocol and stream name
+// from any source. An empty streamName matches all streams of the sub-protocol.
+// A previous registration for the same key is replaced.
+func (m *Mux) Handle(subProto SubProtocol, streamName string, handler Handler) {
+	m.HandleFrom(subProto, streamName, nil, handler)
+}
+
+// HandleFunc registers the handler function for the given sub-protocol and stream name.
+func (m *Mux) HandleFunc(subProto SubProtocol, streamName string, handler func(p *Packet, addr *net.UDPAddr)) {
+	m.Handle(subProto, streamName, HandlerFunc(handler))
+}
+
+// HandleFrom registers the handler for packets of the given sub-protocol and stream name
+// sent from the source IP. A nil source matches any source, an empty streamName matches
+// all streams. A previous registration for the same key is replaced; a nil handler
+// removes it.
+func (m *Mux) HandleFrom(subProto SubProtocol, streamName string, source net.IP, handler Handler) {
+	subProto &= ProtocolMask
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, e := range m.entries {
+		if e.subProto == subProto && e.streamName == streamName && e.source.Equal(source) {
+			if handler == nil {
+				m.entries = append(m.entries[:i], m.entries[i+1:]...)
+			} else {
+				m.entries[i].handler = handler
+			}
+			return
+		}
+	}
+	if handler != nil {
+		m.entries = append(m.entries, muxEntry{
+			subProto:   subProto,
+			streamName: streamName,
+			source:     source,
+			handler:    handler,
+		})
+	}
+}
+
+// Remove unregisters the handler for the given sub-protocol, stream name and source.
+func (m *Mux) Remove(subProto SubProtocol, streamName string, source net.IP) {
+	m.HandleFrom(subProto, streamName, source, nil)
+}
+
+// HandleUnmatched sets the handler called for packets that match no registration.
+// A nil handler discards such packets (the default).
+func (m *Mux) HandleUnmatched(handler Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.unmatched = handler
+}
+
+// Handler returns the handler that would receive a packet with the given header and
+// source address, or nil if the packet would be discarded.
+func (m *Mux) Handler(h *Header, addr *net.UDPAddr) Handler {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var best *muxEntry
+	for i := range m.entries {
+		e := &m.entries[i]
+		if e.matches(h, addr) && (best == nil || e.specificity() > best.specificity()) {
+			best = e
+		}
+	}
+	if best != nil {
+		return best.handler
+	}
+	return m.unmatched
+}
+
+// HandlePacket dispatches the packet to the matching handler.
+func (m *Mux) HandlePacket(p *Packet, addr *net.UDPAddr) {
+	if p == nil {
+		return
+	}
+	if h := m.Handler(&p.Header, addr); h != nil {
+		h.HandlePacket(p, addr)
+	}
+}
+
+// Serve reads packets from conn in the calling goroutine and dispatches them until the
+// connection is closed or fails. Malformed packets are skipped. Serve returns nil if the
+// connection was closed, and the read error otherwise. Handlers run synchronously on the
+// reading goroutine, so slow handlers delay all other streams.
+func (m *Mux) Serve(conn *Conn) error {
+	return serve(conn, m)
+}
+
+// serve reads packets from conn and passes them to h until the connection is closed
+// (returning nil) or fails. Malformed packets are skipped.
+func serve(conn *Conn, h Handler) error {
+	if conn == nil {
+		return errors.New("connection cannot be nil")
+	}
+	for {
+		packet, addr, err := conn.Receive()
+		if err != nil {
+			if addr != nil {
+				continue // Data was received but was not a valid VBAN packet
+			}
+			if errors.Is(err, ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		h.HandlePacket(packet, addr)
+	}
+}
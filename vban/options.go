@@ -0,0 +1,120 @@
+package vban
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// DSCPExpedited is the Expedited Forwarding DSCP value (EF, 46), recommended for
+// real-time audio. See WithDSCP.
+const DSCPExpedited = 46
+
+// errSockoptUnsupported is returned when socket options cannot be set on this platform.
+var errSockoptUnsupported = errors.New("socket option is not supported on this platform")
+
+// Option configures the socket created by Listen, Dial and the other Conn constructors.
+// Options are applied before the socket is bound, so they also take effect for packets
+// received or sent right away.
+type Option func(*options)
+
+// options holds the socket settings collected from Options.
+type options struct {
+	readBuffer  int    // SO_RCVBUF in bytes (unchanged if 0)
+	writeBuffer int    // SO_SNDBUF in bytes (unchanged if 0)
+	dscp        int    // DSCP of sent packets (if setDSCP)
+	setDSCP     bool   // Whether dscp was set
+	reusePort   bool   // SO_REUSEPORT
+	broadcast   bool   // SO_BROADCAST
+	iface       string // Interface to bind to (any if empty)
+}
+
+// WithReadBuffer sets the size of the socket receive buffer in bytes (SO_RCVBUF). A larger
+// buffer absorbs bursts when the receiving goroutine is briefly delayed, e.g. 4 MiB for
+// many multichannel streams. The kernel may limit or adjust the size; on Linux the limit
+// is net.core.rmem_max.
+func WithReadBuffer(bytes int) Option {
+	return func(o *options) { o.readBuffer = bytes }
+}
+
+// WithWriteBuffer sets the size of the socket send buffer in bytes (SO_SNDBUF). On Linux
+// the limit is net.core.wmem_max.
+func WithWriteBuffer(bytes int) Option {
+	return func(o *options) { o.writeBuffer = bytes }
+}
+
+// WithDSCP marks sent packets with the given Differentiated Services Code Point (0-63),
+// e.g. DSCPExpedited, so that QoS-enabled networks prioritize them. It sets IP_TOS for
+// IPv4 and IPV6_TCLASS for IPv6; ECN bits are left zero.
+func WithDSCP(dscp int) Option {
+	return func(o *options) { o.dscp, o.setDSCP = dscp, true }
+}
+
+// WithReusePort allows several sockets, possibly in different processes, to bind the same
+// port (SO_REUSEPORT), e.g. 6980. All of them must set the option. Broadcast and multicast
+// packets are delivered to every socket; on Linux, unicast packets are distributed among
+// the sockets by sender address, so each stream is received by only one of them.
+func WithReusePort() Option {
+	return func(o *options) { o.reusePort = true }
+}
+
+// WithBroadcast enables sending to broadcast addresses (SO_BROADCAST), like SetBroadcast.
+func WithBroadcast() Option {
+	return func(o *options) { o.broadcast = true }
+}
+
+// WithInterface binds the socket to the named network interface (e.g. "eth1"), so that it
+// only receives packets arriving on it and sends through it regardless of the routing
+// table. It is supported on Linux (SO_BINDTODEVICE, which requires CAP_NET_RAW before
+// Linux 5.7) and macOS.
+func WithInterface(name string) Option {
+	return func(o *options) { o.iface = name }
+}
+
+// newOptions applies opts to the default settings.
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// control sets the socket options on a new socket before it is bound or connected. It
+// implements the Control hook of net.ListenConfig and net.Dialer.
+func (o *options) control(network, address string, c syscall.RawConn) error {
+	if o.readBuffer > 0 {
+		if err := setReadBuffer(c, o.readBuffer); err != nil {
+			return fmt.Errorf("failed to set receive buffer size: %w", err)
+		}
+	}
+	if o.writeBuffer > 0 {
+		if err := setWriteBuffer(c, o.writeBuffer); err != nil {
+			return fmt.Errorf("failed to set send buffer size: %w", err)
+		}
+	}
+	if o.setDSCP {
+		if o.dscp < 0 || o.dscp > 63 {
+			return fmt.Errorf("DSCP must be between 0 and 63, got %d", o.dscp)
+		}
+		if err := setTrafficClass(c, network, o.dscp<<2); err != nil {
+			return fmt.Errorf("failed to set DSCP: %w", err)
+		}
+	}
+	if o.reusePort {
+		if err := setReusePort(c); err != nil {
+			return fmt.Errorf("failed to enable port reuse: %w", err)
+		}
+	}
+	if o.broadcast {
+		if err := setBroadcast(c, true); err != nil {
+			return fmt.Errorf("failed to enable broadcast: %w", err)
+		}
+	}
+	if o.iface != "" {
+		if err := bindToInterface(c, network, o.iface); err != nil {
+			return fmt.Errorf("failed to bind to interface %s: %w", o.iface, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,339 @@
+package vban
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// --- PINGO Device Identification Service (Spec p.24-26) ---
+
+// PingPacketSize is the size in bytes of the PING0 payload structure.
+const PingPacketSize = 676
+
+// PingDeviceType describes the kind of VBAN device (bitType field of PING0).
+type PingDeviceType uint32
+
+const (
+	PingTypeReceptor        PingDeviceType = 0x00000001 // Simple receptor
+	PingTypeTransmitter     PingDeviceType = 0x00000002 // Simple transmitter
+	PingTypeReceptorSpot    PingDeviceType = 0x00000004 // Software or hardware receptor with remote controls
+	PingTypeTransmitterSpot PingDeviceType = 0x00000008 // Software or hardware transmitter with remote controls
+	PingTypeVirtualDevice   PingDeviceType = 0x00000010 // Virtual audio device
+	PingTypeVirtualMixer    PingDeviceType = 0x00000020 // Virtual mixer
+	PingTypeMatrix          PingDeviceType = 0x00000040 // Matrix
+	PingTypeDAW             PingDeviceType = 0x00000080 // Digital audio workstation
+	PingTypeServer          PingDeviceType = 0x01000000 // VBAN server
+)
+
+// PingFeature describes the capabilities of a VBAN device (bitfeature field of PING0).
+type PingFeature uint32
+
+const (
+	PingFeatureAudio  PingFeature = 0x00000001 // Audio streams (PCM)
+	PingFeatureAOIP   PingFeature = 0x00000002 // Audio streams using the VBCA codec
+	PingFeatureVOIP   PingFeature = 0x00000004 // Audio streams using the VBCV codec
+	PingFeatureSerial PingFeature = 0x00000100 // Serial streams
+	PingFeatureMIDI   PingFeature = 0x00000300 // MIDI streams (implies Serial)
+	PingFeatureFrame  PingFeature = 0x00001000 // Frame (video) streams
+	PingFeatureText   PingFeature = 0x00010000 // Text streams
+)
+
+// pingFeatureNames lists the features in the order used by PingFeature.String.
+var pingFeatureNames = []struct {
+	feature PingFeature
+	name    string
+}{
+	{PingFeatureAudio, "AUDIO"},
+	{PingFeatureAOIP, "AOIP"},
+	{PingFeatureVOIP, "VOIP"},
+	{PingFeatureMIDI, "MIDI"},
+	{PingFeatureSerial, "SERIAL"},
+	{PingFeatureFrame, "FRAME"},
+	{PingFeatureText, "TXT"},
+}
+
+// Has reports whether all bits of feature are set.
+func (f PingFeature) Has(feature PingFeature) bool { return f&feature == feature }
+
+// String returns the feature names separated by '|', e.g. "AUDIO|MIDI|TXT".
+func (f PingFeature) String() string {
+	var names []string
+	var seen PingFeature
+	for _, fn := range pingFeatureNames {
+		if f.Has(fn.feature) && seen&fn.feature != fn.feature {
+			names = append(names, fn.name)
+			seen |= fn.feature
+		}
+	}
+	if rest := f &^ seen; rest != 0 {
+		names = append(names, fmt.Sprintf("0x%X", uint32(rest)))
+	}
+	return strings.Join(names, "|")
+}
+
+// PingPacket is the PING0 payload carried by Identification service requests and replies.
+// String fields are truncated to the size of the corresponding field when marshaled.
+type PingPacket struct {
+	DeviceType       PingDeviceType // VBAN device type
+	Features         PingFeature    // VBAN features
+	FeaturesEx       uint32         // VBAN feature extension
+	PreferredRate    uint32         // Preferred sample rate (Hz)
+	MinRate          uint32         // Minimum supported sample rate (Hz)
+	MaxRate          uint32         // Maximum supported sample rate (Hz)
+	Color            uint32         // User color (0x00RRGGBB)
+	Version          [4]byte        // Application version, e.g. {1, 0, 0, 0}
+	GPSPosition      string         // 8 bytes: Device position
+	UserPosition     string         // 8 bytes: Device position defined by the user
+	LangCode         string         // 8 bytes: Main language used by the user (e.g. "EN")
+	DistantIP        string         // 32 bytes: Distant IP
+	DistantPort      uint16         // Distant port
+	DeviceName       string         // 64 bytes: Physical device name
+	ManufacturerName string         // 64 bytes: Manufacturer name
+	ApplicationName  string         // 64 bytes: Application name
+	HostName         string         // 64 bytes: DNS host name
+	UserName         string         // 128 bytes (UTF-8): User name
+	UserComment      string         // 128 bytes (UTF-8): User comment, mood or message
+}
+
+// Field offsets within the PING0 structure.
+const (
+	pingOffsetVersion      = 28
+	pingOffsetGPS          = 32
+	pingOffsetUserPos      = 40
+	pingOffsetLang         = 48
+	pingOffsetDistantIP    = 128 // After 8 reserved bytes and 64 bytes of reservedEx
+	pingOffsetDistantPort  = 160
+	pingOffsetDeviceName   = 164 // After 2 bytes of DistantReserved
+	pingOffsetManufacturer = 228
+	pingOffsetApplication  = 292
+	pingOffsetHostName     = 356
+	pingOffsetUserName     = 420
+	pingOffsetUserComment  = 548
+)
+
+// putPingString copies s into a fixed-size, null-padded field without splitting UTF-8 sequences.
+func putPingString(field []byte, s string) {
+	n := len(s)
+	if n > len(field) {
+		n = len(field)
+		for n > 0 && !utf8.RuneStart(s[n]) {
+			n--
+		}
+	}
+	copy(field, s[:n])
+}
+
+// getPingString reads a null-terminated string from a fixed-size field.
+func getPingString(field []byte) string {
+	if n := bytes.IndexByte(field, 0); n >= 0 {
+		field = field[:n]
+	}
+	return string(field)
+}
+
+// MarshalBinary converts the PingPacket into its 676-byte PING0 representation (Little Endian).
+// Reserved fields are zeroed.
+func (pp *PingPacket) MarshalBinary() ([]byte, error) {
+	b := make([]byte, PingPacketSize)
+	byteOrder.PutUint32(b[0:], uint32(pp.DeviceType))
+	byteOrder.PutUint32(b[4:], uint32(pp.Features))
+	byteOrder.PutUint32(b[8:], pp.FeaturesEx)
+	byteOrder.PutUint32(b[12:], pp.PreferredRate)
+	byteOrder.PutUint32(b[16:], pp.MinRate)
+	byteOrder.PutUint32(b[20:], pp.MaxRate)
+	byteOrder.PutUint32(b[24:], pp.Color)
+	copy(b[pingOffsetVersion:], pp.Version[:])
+	putPingString(b[pingOffsetGPS:pingOffsetUserPos], pp.GPSPosition)
+	putPingString(b[pingOffsetUserPos:pingOffsetLang], pp.UserPosition)
+	putPingString(b[pingOffsetLang:pingOffsetLang+8], pp.LangCode)
+	putPingString(b[pingOffsetDistantIP:pingOffsetDistantPort], pp.DistantIP)
+	byteOrder.PutUint16(b[pingOffsetDistantPort:], pp.DistantPort)
+	putPingString(b[pingOffsetDeviceName:pingOffsetManufacturer], pp.DeviceName)
+	putPingString(b[pingOffsetManufacturer:pingOffsetApplication], pp.ManufacturerName)
+	putPingString(b[pingOffsetApplication:pingOffsetHostName], pp.ApplicationName)
+	putPingString(b[pingOffsetHostName:pingOffsetUserName], pp.HostName)
+	putPingString(b[pingOffsetUserName:pingOffsetUserComment], pp.UserName)
+	putPingString(b[pingOffsetUserComment:PingPacketSize], pp.UserComment)
+	return b, nil
+}
+
+// UnmarshalBinary parses a PING0 payload (Little Endian) into the PingPacket.
+// Data beyond PingPacketSize is ignored.
+func (pp *PingPacket) UnmarshalBinary(data []byte) error {
+	if len(data) < PingPacketSize {
+		return fmt.Errorf("insufficient data for PING0 payload: expected %d bytes, got %d", PingPacketSize, len(data))
+	}
+	b := data[:PingPacketSize]
+	pp.DeviceType = PingDeviceType(byteOrder.Uint32(b[0:]))
+	pp.Features = PingFeature(byteOrder.Uint32(b[4:]))
+	pp.FeaturesEx = byteOrder.Uint32(b[8:])
+	pp.PreferredRate = byteOrder.Uint32(b[12:])
+	pp.MinRate = byteOrder.Uint32(b[16:])
+	pp.MaxRate = byteOrder.Uint32(b[20:])
+	pp.Color = byteOrder.Uint32(b[24:])
+	copy(pp.Version[:], b[pingOffsetVersion:])
+	pp.GPSPosition = getPingString(b[pingOffsetGPS:pingOffsetUserPos])
+	pp.UserPosition = getPingString(b[pingOffsetUserPos:pingOffsetLang])
+	pp.LangCode = getPingString(b[pingOffsetLang : pingOffsetLang+8])
+	pp.DistantIP = getPingString(b[pingOffsetDistantIP:pingOffsetDistantPort])
+	pp.DistantPort = byteOrder.Uint16(b[pingOffsetDistantPort:])
+	pp.DeviceName = getPingString(b[pingOffsetDeviceName:pingOffsetManufacturer])
+	pp.ManufacturerName = getPingString(b[pingOffsetManufacturer:pingOffsetApplication])
+	pp.ApplicationName = getPingString(b[pingOffsetApplication:pingOffsetHostName])
+	pp.HostName = getPingString(b[pingOffsetHostName:pingOffsetUserName])
+	pp.UserName = getPingString(b[pingOffsetUserName:pingOffsetUserComment])
+	pp.UserComment = getPingString(b[pingOffsetUserComment:PingPacketSize])
+	return nil
+}
+
+// NewPingPacket builds an Identification service packet carrying info.
+// Requests use ServiceFuncPing, replies use ServiceFuncReply with the request's ID.
+func NewPingPacket(function ServiceFunction, requestID uint32, info *PingPacket) (*Packet, error) {
+	if info == nil {
+		info = &PingPacket{}
+	}
+	data, err := info.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal PING0 payload: %w", err)
+	}
+	return NewPacket(NewServiceHeader(ServiceIdentification, function, requestID), data)
+}
+
+// isPing reports whether p is an Identification service packet with the given reply flag.
+func isPing(p *Packet, reply bool) bool {
+	return p.Header.SubProtocol().IsService() &&
+		p.Header.ServiceType() == ServiceIdentification &&
+		p.Header.ServiceFunction().IsReply() == reply
+}
+
+// PingResponder is a Handler that answers Identification ping requests with its device
+// information, making the application visible in other VBAN devices (e.g. the Voicemeeter
+// VBAN panel). Register it on a Mux for ProtocolService, or call Run. It is safe for
+// concurrent use.
+type PingResponder struct {
+	conn *Conn
+
+	mu   sync.RWMutex
+	info PingPacket
+}
+
+// NewPingResponder creates a responder answering pings received on conn with info.
+func NewPingResponder(conn *Conn, info PingPacket) *PingResponder {
+	return &PingResponder{conn: conn, info: info}
+}
+
+// SetInfo replaces the device information sent in replies.
+func (r *PingResponder) SetInfo(info PingPacket) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.info = info
+}
+
+// Info returns the device information sent in replies.
+func (r *PingResponder) Info() PingPacket {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.info
+}
+
+// HandlePacket replies to Identification ping requests. Other packets are ignored.
+func (r *PingResponder) HandlePacket(p *Packet, addr *net.UDPAddr) {
+	if p == nil || addr == nil || !isPing(p, false) {
+		return
+	}
+	info := r.Info()
+	reply, err := NewPingPacket(ServiceFuncReply, p.Header.NuFrame, &info)
+	if err != nil {
+		return
+	}
+	_ = r.conn.Send(reply, addr) // Best effort, as for any UDP reply
+}
+
+// Run reads packets from the responder's Conn and answers pings until the connection is
+// closed (returning nil) or fails. Use a Mux instead if the Conn also carries streams.
+func (r *PingResponder) Run() error {
+	return serve(r.conn, r)
+}
+
+// PingReply is a reply received by Ping.
+type PingReply struct {
+	Addr *net.UDPAddr  // Address the reply was sent from
+	Info PingPacket    // Device information of the replying device
+	RTT  time.Duration // Time between sending the request and receiving the reply
+}
+
+// Ping sends an Identification request carrying info (which may be nil) to addr and
+// collects the replies received within timeout. addr may be nil for a dialed Conn.
+// Ping reads from conn itself and discards unrelated packets, so conn should not be
+// read concurrently (e.g. by a Mux).
+func Ping(conn *Conn, addr *net.UDPAddr, info *PingPacket, timeout time.Duration) ([]PingReply, error) {
+	var replies []PingReply
+	err := ping(context.Background(), conn, addr, info, time.Now().Add(timeout), func(reply PingReply) {
+		replies = append(replies, reply)
+	}, nil)
+	return replies, err
+}
+
+// ping sends an Identification request and passes every matching reply received before
+// the deadline to fn, and every stream packet (any sub-protocol but service) to streams
+// if it is not nil. It returns early with ctx.Err() if ctx is canceled.
+func ping(ctx context.Context, conn *Conn, addr *net.UDPAddr, info *PingPacket, deadline time.Time, fn func(PingReply), streams func(*Packet, *net.UDPAddr)) error {
+	if conn == nil {
+		return ErrClosed
+	}
+	if _, err := conn.conn(); err != nil {
+		return err
+	}
+	requestID := rand.Uint32()
+	request, err := NewPingPacket(ServiceFuncPing, requestID, info)
+	if err != nil {
+		return err
+	}
+
+	readCtx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+	start := time.Now()
+	if err := conn.SendContext(readCtx, request, addr); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("failed to send ping request: %w", err)
+	}
+	for {
+		packet, from, err := conn.ReceiveContext(readCtx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil
+			}
+			if from != nil {
+				continue // Not a valid VBAN packet
+			}
+			return err
+		}
+		if !packet.Header.SubProtocol().IsService() {
+			if streams != nil {
+				streams(packet, from)
+			}
+			continue
+		}
+		if !isPing(packet, true) || packet.Header.NuFrame != requestID {
+			continue
+		}
+		reply := PingReply{Addr: from, RTT: time.Since(start)}
+		if err := reply.Info.UnmarshalBinary(packet.Data); err != nil {
+			continue
+		}
+		fn(reply)
+	}
+}
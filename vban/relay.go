@@ -0,0 +1,182 @@
+package vban
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+)
+
+// RelayRoute selects packets to forward and describes how they are rewritten.
+type RelayRoute struct {
+	StreamName string       // Stream to forward (any stream if empty)
+	Source     *net.UDPAddr // Optional: only forward packets from this IP (and port, if non-zero)
+
+	// Protocols lists the sub-protocols to forward. If empty, audio, serial and text
+	// packets are forwarded; service packets are only forwarded if listed explicitly.
+	Protocols []SubProtocol
+
+	Rename   string // New stream name (unchanged if empty)
+	Renumber bool   // Replace NuFrame with a continuous counter of the packets forwarded by this route
+
+	Destinations []*net.UDPAddr // Addresses the packets are sent to (at least one)
+}
+
+// RelayConfig configures a Relay.
+type RelayConfig struct {
+	Routes []RelayRoute
+
+	// OnError, if set, is called when a packet cannot be sent to a destination. The relay
+	// continues with the remaining destinations; the failures of a packet are reported
+	// once it has been sent to all of them.
+	OnError func(dst *net.UDPAddr, err error)
+}
+
+// RelayStats holds the counters of a Relay.
+type RelayStats struct {
+	Received  uint64 // Packets handled
+	Matched   uint64 // Packets matching at least one route
+	Forwarded uint64 // Packets sent (counted once per destination)
+	Errors    uint64 // Failed sends
+}
+
+// relayRoute is a validated route with its frame counter.
+type relayRoute struct {
+	RelayRoute
+	nuFrame uint32 // Next NuFrame when renumbering
+}
+
+// Relay receives VBAN packets and forwards selected streams to one or more destinations,
+// optionally renaming them and renumbering their frame counters, e.g. to fan a single
+// program feed out to many endpoints. A packet matching several routes is forwarded by
+// each of them. Packets are forwarded from the receiving goroutine as they arrive.
+// Relay is safe for concurrent use.
+type Relay struct {
+	in  *Conn
+	out *Conn
+	cfg RelayConfig
+
+	routes []*relayRoute // Fixed after construction; r.mu guards their frame counters
+
+	mu    sync.Mutex
+	stats RelayStats
+}
+
+// NewRelay creates a relay receiving from in and sending through out. If out is nil,
+// packets are sent through in. in may be nil if packets are delivered through
+// HandlePacket only (e.g. by a Mux), in which case out is required.
+func NewRelay(in, out *Conn, cfg RelayConfig) (*Relay, error) {
+	if out == nil {
+		out = in
+	}
+	if out == nil {
+		return nil, errors.New("connection cannot be nil")
+	}
+	if len(cfg.Routes) == 0 {
+		return nil, errors.New("at least one route is required")
+	}
+	r := &Relay{in: in, out: out, cfg: cfg}
+	for i, route := range cfg.Routes {
+		if len(route.Destinations) == 0 {
+			return nil, fmt.Errorf("route %d has no destinations", i)
+		}
+		for _, dst := range route.Destinations {
+			if dst == nil {
+				return nil, fmt.Errorf("route %d has a nil destination", i)
+			}
+		}
+		if len(route.Rename) > MaxStreamNameLen {
+			return nil, fmt.Errorf("route %d: stream name %q exceeds %d bytes", i, route.Rename, MaxStreamNameLen)
+		}
+		r.routes = append(r.routes, &relayRoute{RelayRoute: route})
+	}
+	return r, nil
+}
+
+// matches reports whether the route forwards a packet with header h from addr.
+func (rt *relayRoute) matches(h *Header, addr *net.UDPAddr) bool {
+	proto := h.SubProtocol() & ProtocolMask
+	if len(rt.Protocols) == 0 {
+		if proto == ProtocolService {
+			return false
+		}
+	} else if !slices.ContainsFunc(rt.Protocols, func(p SubProtocol) bool { return p&ProtocolMask == proto }) {
+		return false
+	}
+	if rt.StreamName != "" && h.GetStreamName() != rt.StreamName {
+		return false
+	}
+	return matchSource(rt.Source, addr)
+}
+
+// relayFailure is a failed send, reported to OnError once the packet has been forwarded.
+type relayFailure struct {
+	dst *net.UDPAddr
+	err error
+}
+
+// HandlePacket forwards a received packet along all matching routes. Sends and OnError
+// calls happen without r.mu held, so a slow destination does not block Stats.
+func (r *Relay) HandlePacket(p *Packet, addr *net.UDPAddr) {
+	if p == nil {
+		return
+	}
+	matched := false
+	var forwarded uint64
+	var failures []relayFailure
+	for _, rt := range r.routes {
+		if !rt.matches(&p.Header, addr) {
+			continue
+		}
+		matched = true
+		out := Packet{Header: p.Header, Data: p.Data}
+		if rt.Rename != "" {
+			out.Header.SetStreamName(rt.Rename)
+		}
+		if rt.Renumber {
+			r.mu.Lock()
+			out.Header.NuFrame = rt.nuFrame
+			rt.nuFrame++
+			r.mu.Unlock()
+		}
+		for _, dst := range rt.Destinations {
+			if err := r.out.Send(&out, dst); err != nil {
+				failures = append(failures, relayFailure{dst: dst, err: err})
+				continue
+			}
+			forwarded++
+		}
+	}
+
+	r.mu.Lock()
+	r.stats.Received++
+	if matched {
+		r.stats.Matched++
+	}
+	r.stats.Forwarded += forwarded
+	r.stats.Errors += uint64(len(failures))
+	r.mu.Unlock()
+
+	if r.cfg.OnError != nil {
+		for _, f := range failures {
+			r.cfg.OnError(f.dst, f.err)
+		}
+	}
+}
+
+// Run reads packets from the relay's input Conn and forwards them until the connection is
+// closed (returning nil) or fails. Malformed packets are skipped.
+func (r *Relay) Run() error {
+	if r.in == nil {
+		return errors.New("relay has no input connection")
+	}
+	return serve(r.in, r)
+}
+
+// Stats returns a snapshot of the relay's counters.
+func (r *Relay) Stats() RelayStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
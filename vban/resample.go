@@ -0,0 +1,261 @@
+package vban
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Float32Reader is implemented by sources of normalized interleaved samples, such as
+// AudioReceiver and Resampler. ReadFloat32 follows the io.Reader conventions: it blocks
+// until at least one sample is available and returns io.EOF at the end of the stream.
+type Float32Reader interface {
+	ReadFloat32(dst []float32) (int, error)
+}
+
+// ResampleQuality selects the length and resolution of a Resampler's interpolation filter.
+// Higher qualities attenuate aliasing better at the cost of CPU time and latency.
+type ResampleQuality int
+
+const (
+	ResampleLow    ResampleQuality = iota // 16 taps, 64 phases: lowest latency and CPU use
+	ResampleMedium                        // 32 taps, 128 phases: suitable for most monitoring
+	ResampleHigh                          // 64 taps, 256 phases: best stopband attenuation
+)
+
+// resampleParams holds the filter design of a ResampleQuality.
+type resampleParams struct {
+	taps   int     // Filter length in input samples (at unity ratio)
+	phases int     // Number of tabulated fractional positions
+	beta   float64 // Kaiser window shape
+	cutoff float64 // Passband edge relative to the lower Nyquist frequency
+}
+
+var resamplePresets = map[ResampleQuality]resampleParams{
+	ResampleLow:    {taps: 16, phases: 64, beta: 6, cutoff: 0.85},
+	ResampleMedium: {taps: 32, phases: 128, beta: 8, cutoff: 0.91},
+	ResampleHigh:   {taps: 64, phases: 256, beta: 10, cutoff: 0.95},
+}
+
+// Resampler converts a stream of interleaved float32 samples from one sample rate to
+// another, for example a 44100 Hz VBAN stream (SRIndex 16) to 48000 Hz. It reads from a
+// Float32Reader, such as an AudioReceiver, and is itself a Float32Reader, so it can be
+// placed between a stream receiver and a consumer.
+//
+// The conversion uses a polyphase windowed-sinc (Kaiser) filter whose phases are linearly
+// interpolated, which supports arbitrary rational and irrational ratios. When
+// downsampling, the filter is widened so that its cutoff follows the output Nyquist
+// frequency. The added latency is half the filter length, i.e. Delay() input frames.
+//
+// The channel count is fixed at construction. Resampler is not safe for concurrent use.
+type Resampler struct {
+	src      Float32Reader
+	channels int
+	step     float64 // Input frames advanced per output frame
+
+	half   int         // Half the filter length in input frames
+	phases int         // Number of tabulated phases
+	table  [][]float32 // phases+1 rows of 2*half taps
+	w      []float32   // Interpolated taps for the current output frame
+
+	buf []float32 // Buffered input samples (interleaved)
+	pos float64   // Position of the next output frame in buf, in frames
+	in  []float32 // Read buffer for src
+	err error     // Pending error from src
+	eof bool      // src is exhausted and the tail has been padded
+}
+
+// NewResampler creates a Resampler converting channels-channel audio read from src from
+// inRate to outRate (in Hz) using the given quality preset.
+func NewResampler(src Float32Reader, channels int, inRate, outRate uint32, quality ResampleQuality) (*Resampler, error) {
+	if src == nil {
+		return nil, errors.New("source cannot be nil")
+	}
+	if channels < 1 {
+		return nil, fmt.Errorf("invalid channel count: %d", channels)
+	}
+	if inRate == 0 || outRate == 0 {
+		return nil, fmt.Errorf("invalid sample rates: %d Hz to %d Hz", inRate, outRate)
+	}
+	params, ok := resamplePresets[quality]
+	if !ok {
+		return nil, fmt.Errorf("unknown resample quality: %d", quality)
+	}
+	r := &Resampler{
+		src:      src,
+		channels: channels,
+		step:     float64(inRate) / float64(outRate),
+	}
+	r.design(params)
+
+	// Prime the buffer so that the first output frame is aligned with the first input frame.
+	r.buf = make([]float32, (r.half-1)*channels)
+	r.pos = float64(r.half - 1)
+	r.in = make([]float32, 256*channels)
+	return r, nil
+}
+
+// design computes the filter table for the current ratio.
+func (r *Resampler) design(params resampleParams) {
+	cutoff := params.cutoff
+	if r.step > 1 {
+		cutoff /= r.step // Downsampling: filter at the output Nyquist frequency
+	}
+	r.half = int(math.Ceil(float64(params.taps) / 2 * params.cutoff / cutoff))
+	r.phases = params.phases
+	r.table = make([][]float32, r.phases+1)
+	i0Beta := besselI0(params.beta)
+	for p := range r.table {
+		row := make([]float32, 2*r.half)
+		frac := float64(p) / float64(r.phases)
+		var sum float64
+		taps := make([]float64, len(row))
+		for k := range taps {
+			t := float64(k-r.half+1) - frac // Distance from the output position in input frames
+			x := t / float64(r.half)
+			if x <= -1 || x >= 1 {
+				continue
+			}
+			window := besselI0(params.beta*math.Sqrt(1-x*x)) / i0Beta
+			taps[k] = cutoff * sinc(cutoff*t) * window
+			sum += taps[k]
+		}
+		for k, v := range taps {
+			row[k] = float32(v / sum) // Normalize for unity gain at DC
+		}
+		r.table[p] = row
+	}
+	r.w = make([]float32, 2*r.half)
+}
+
+// sinc returns the normalized sinc function sin(pi x) / (pi x).
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// besselI0 returns the zeroth-order modified Bessel function of the first kind.
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; k < 50; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+		if term < sum*1e-12 {
+			break
+		}
+	}
+	return sum
+}
+
+// Channels returns the number of interleaved channels.
+func (r *Resampler) Channels() int { return r.channels }
+
+// Ratio returns the current conversion ratio (input frames per output frame).
+func (r *Resampler) Ratio() float64 { return r.step }
+
+// SetRatio changes the conversion ratio (input frames per output frame) for subsequent
+// output. It is intended for small adjustments, such as clock drift compensation; the
+// filter cutoff stays designed for the ratio given at construction.
+func (r *Resampler) SetRatio(ratio float64) {
+	if ratio > 0 {
+		r.step = ratio
+	}
+}
+
+// Delay returns the latency added by the filter, in input frames.
+func (r *Resampler) Delay() int { return r.half }
+
+// ReadFloat32 reads resampled interleaved samples into dst, which should hold at least one
+// frame. Only whole frames are returned. It blocks until at least one frame is available
+// and returns io.EOF once the source is exhausted and the filter tail has been flushed.
+func (r *Resampler) ReadFloat32(dst []float32) (int, error) {
+	ch := r.channels
+	if len(dst) < ch {
+		return 0, io.ErrShortBuffer
+	}
+	n := 0
+	for n+ch <= len(dst) {
+		i := int(r.pos)
+		// Frames i-half+1 ... i+half must be buffered.
+		if len(r.buf)/ch <= i+r.half {
+			if r.eof {
+				break
+			}
+			if n > 0 && r.err == nil {
+				break // Do not block once some output is available
+			}
+			if err := r.fill(); err != nil {
+				if n > 0 {
+					return n, nil
+				}
+				return 0, err
+			}
+			continue
+		}
+
+		// Interpolate the taps between the two nearest phases.
+		phase := (r.pos - float64(i)) * float64(r.phases)
+		p := int(phase)
+		a := float32(phase - float64(p))
+		lo, hi := r.table[p], r.table[p+1]
+		for k := range r.w {
+			r.w[k] = lo[k] + a*(hi[k]-lo[k])
+		}
+
+		in := r.buf[(i-r.half+1)*ch:]
+		out := dst[n : n+ch]
+		clear(out)
+		for k, w := range r.w {
+			frame := in[k*ch : k*ch+ch]
+			for c := range out {
+				out[c] += w * frame[c]
+			}
+		}
+		n += ch
+		r.pos += r.step
+	}
+	r.compact()
+	if n == 0 && r.eof {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// fill appends samples read from the source to the buffer. When the source reports an
+// error, the buffered tail is padded with silence so that it can be flushed, and the
+// error is returned once nothing is left to output.
+func (r *Resampler) fill() error {
+	if r.err != nil {
+		if r.err != io.EOF {
+			return r.err
+		}
+		// Pad with silence to flush the filter, keeping whole frames only.
+		r.buf = r.buf[:len(r.buf)-len(r.buf)%r.channels]
+		r.buf = append(r.buf, make([]float32, r.half*r.channels)...)
+		r.eof = true
+		return nil
+	}
+	n, err := r.src.ReadFloat32(r.in)
+	r.buf = append(r.buf, r.in[:n]...)
+	if err != nil {
+		r.err = err
+	}
+	if n == 0 && err == nil {
+		return io.ErrNoProgress
+	}
+	return nil
+}
+
+// compact discards buffered frames that are no longer needed.
+func (r *Resampler) compact() {
+	drop := int(r.pos) - r.half + 1
+	if drop <= 0 || drop*r.channels < len(r.buf)/2 {
+		return
+	}
+	n := copy(r.buf, r.buf[drop*r.channels:])
+	r.buf = r.buf[:n]
+	r.pos -= float64(drop)
+}
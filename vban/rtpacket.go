@@ -0,0 +1,311 @@
+package vban
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// --- RT-Packet Service (Voicemeeter real-time state) ---
+
+// RTPacketSize is the size in bytes of the VBAN_VMRT_PACKET structure.
+const RTPacketSize = 1384
+
+// RTPacketRegisterStreamName is the stream name used by RT-Packet registration requests.
+const RTPacketRegisterStreamName = "Register RTP"
+
+// RTPacketIDVoicemeeter is the RT packet ID of the VBAN_VMRT_PACKET structure.
+const RTPacketIDVoicemeeter ServiceFunction = 0
+
+// DefaultRTPacketTimeout is the registration timeout used when RTPacketClientConfig.Timeout is zero.
+const DefaultRTPacketTimeout = 15 * time.Second
+
+// VoicemeeterType identifies the Voicemeeter edition sending RT packets.
+type VoicemeeterType uint8
+
+const (
+	VoicemeeterStandard VoicemeeterType = 1 // Voicemeeter (3 strips, 2 buses)
+	VoicemeeterBanana   VoicemeeterType = 2 // Voicemeeter Banana (5 strips, 5 buses)
+	VoicemeeterPotato   VoicemeeterType = 3 // Voicemeeter Potato, also called Voicemeeter 8 (8 strips, 8 buses)
+)
+
+// String returns the name of the Voicemeeter edition.
+func (t VoicemeeterType) String() string {
+	switch t {
+	case VoicemeeterStandard:
+		return "Voicemeeter"
+	case VoicemeeterBanana:
+		return "Voicemeeter Banana"
+	case VoicemeeterPotato:
+		return "Voicemeeter Potato"
+	default:
+		return fmt.Sprintf("VoicemeeterType(%d)", uint8(t))
+	}
+}
+
+// StripMode holds the button states of a Voicemeeter strip or bus (VMRTSTATE_MODE_* bits).
+type StripMode uint32
+
+const (
+	ModeMute       StripMode = 0x00000001
+	ModeSolo       StripMode = 0x00000002
+	ModeMono       StripMode = 0x00000004
+	ModeMuteC      StripMode = 0x00000008
+	ModeMixdown    StripMode = 0x00000010 // Bus mode values (masked with ModeBusMask)
+	ModeRepeat     StripMode = 0x00000020
+	ModeMixdownB   StripMode = 0x00000030
+	ModeComposite  StripMode = 0x00000040
+	ModeTVMix      StripMode = 0x00000050
+	ModeUpMix21    StripMode = 0x00000060
+	ModeUpMix41    StripMode = 0x00000070
+	ModeUpMix61    StripMode = 0x00000080
+	ModeCenterOnly StripMode = 0x00000090
+	ModeLFEOnly    StripMode = 0x000000A0
+	ModeRearOnly   StripMode = 0x000000B0
+	ModeBusMask    StripMode = 0x000000F0
+	ModeEQ         StripMode = 0x00000100
+	ModeCross      StripMode = 0x00000200
+	ModeEQB        StripMode = 0x00000800
+	ModeBusA1      StripMode = 0x00001000 // Strip routed to output bus A1
+	ModeBusA2      StripMode = 0x00002000
+	ModeBusA3      StripMode = 0x00004000
+	ModeBusA4      StripMode = 0x00008000
+	ModeBusA5      StripMode = 0x00080000
+	ModeBusB1      StripMode = 0x00010000 // Strip routed to virtual bus B1
+	ModeBusB2      StripMode = 0x00020000
+	ModeBusB3      StripMode = 0x00040000
+	ModePanColor   StripMode = 0x00100000 // Pan mode values (masked with ModePanMask)
+	ModePanMod     StripMode = 0x00200000
+	ModePanMask    StripMode = 0x00F00000
+	ModePostFxR    StripMode = 0x01000000
+	ModePostFxD    StripMode = 0x02000000
+	ModePostFx1    StripMode = 0x04000000
+	ModePostFx2    StripMode = 0x08000000
+	ModeSel        StripMode = 0x10000000
+	ModeMonitor    StripMode = 0x20000000
+)
+
+// Has reports whether all bits of flag are set.
+func (m StripMode) Has(flag StripMode) bool { return m&flag == flag }
+
+// BusMode returns the bus mode value (ModeMixdown ... ModeRearOnly), or 0 for normal mode.
+func (m StripMode) BusMode() StripMode { return m & ModeBusMask }
+
+// PanMode returns the pan mode value (ModePanColor or ModePanMod), or 0 for the default pan.
+func (m StripMode) PanMode() StripMode { return m & ModePanMask }
+
+// RTPacket is the decoded VBAN_VMRT_PACKET structure describing the real-time state of
+// Voicemeeter. Levels and gains are expressed in dB * 100 as on the wire.
+type RTPacket struct {
+	VoicemeeterType    VoicemeeterType
+	BufferSize         uint16       // Main stream buffer size
+	VoicemeeterVersion uint32       // Version, one byte per component (e.g. 0x03000208 for 3.0.2.8)
+	OptionBits         uint32       // Unused
+	SampleRate         uint32       // Main stream sample rate (Hz)
+	InputLevels        [34]int16    // Pre-fader input peak levels
+	OutputLevels       [64]int16    // Bus output peak levels
+	TransportBit       uint32       // Transport status
+	StripState         [8]StripMode // Strip button states
+	BusState           [8]StripMode // Bus button states
+	StripGain          [8][8]int16  // Strip gains, indexed by [layer][strip]
+	BusGain            [8]int16     // Bus gains
+	StripLabel         [8]string    // Strip labels (UTF-8, up to 60 bytes)
+	BusLabel           [8]string    // Bus labels (UTF-8, up to 60 bytes)
+}
+
+// Field offsets within the VBAN_VMRT_PACKET structure.
+const (
+	rtOffsetInputLevels  = 16
+	rtOffsetOutputLevels = 84
+	rtOffsetTransport    = 212
+	rtOffsetStripState   = 216
+	rtOffsetBusState     = 248
+	rtOffsetStripGain    = 280
+	rtOffsetBusGain      = 408
+	rtOffsetStripLabel   = 424
+	rtOffsetBusLabel     = 904
+	rtLabelSize          = 60
+)
+
+// VersionString returns the Voicemeeter version in dotted form, e.g. "3.0.2.8".
+func (rt *RTPacket) VersionString() string {
+	v := rt.VoicemeeterVersion
+	return fmt.Sprintf("%d.%d.%d.%d", v>>24, (v>>16)&0xFF, (v>>8)&0xFF, v&0xFF)
+}
+
+// DB converts a level or gain in dB * 100 to dB.
+func DB(dB100 int16) float64 { return float64(dB100) / 100 }
+
+// MarshalBinary converts the RTPacket into its 1384-byte representation (Little Endian).
+func (rt *RTPacket) MarshalBinary() ([]byte, error) {
+	b := make([]byte, RTPacketSize)
+	b[0] = uint8(rt.VoicemeeterType)
+	byteOrder.PutUint16(b[2:], rt.BufferSize)
+	byteOrder.PutUint32(b[4:], rt.VoicemeeterVersion)
+	byteOrder.PutUint32(b[8:], rt.OptionBits)
+	byteOrder.PutUint32(b[12:], rt.SampleRate)
+	for i, v := range rt.InputLevels {
+		byteOrder.PutUint16(b[rtOffsetInputLevels+2*i:], uint16(v))
+	}
+	for i, v := range rt.OutputLevels {
+		byteOrder.PutUint16(b[rtOffsetOutputLevels+2*i:], uint16(v))
+	}
+	byteOrder.PutUint32(b[rtOffsetTransport:], rt.TransportBit)
+	for i := range 8 {
+		byteOrder.PutUint32(b[rtOffsetStripState+4*i:], uint32(rt.StripState[i]))
+		byteOrder.PutUint32(b[rtOffsetBusState+4*i:], uint32(rt.BusState[i]))
+		for layer := range 8 {
+			byteOrder.PutUint16(b[rtOffsetStripGain+16*layer+2*i:], uint16(rt.StripGain[layer][i]))
+		}
+		byteOrder.PutUint16(b[rtOffsetBusGain+2*i:], uint16(rt.BusGain[i]))
+		putPingString(b[rtOffsetStripLabel+rtLabelSize*i:rtOffsetStripLabel+rtLabelSize*(i+1)], rt.StripLabel[i])
+		putPingString(b[rtOffsetBusLabel+rtLabelSize*i:rtOffsetBusLabel+rtLabelSize*(i+1)], rt.BusLabel[i])
+	}
+	return b, nil
+}
+
+// UnmarshalBinary parses a VBAN_VMRT_PACKET payload (Little Endian) into the RTPacket.
+func (rt *RTPacket) UnmarshalBinary(data []byte) error {
+	if len(data) < RTPacketSize {
+		return fmt.Errorf("insufficient data for RT packet: expected %d bytes, got %d", RTPacketSize, len(data))
+	}
+	b := data[:RTPacketSize]
+	rt.VoicemeeterType = VoicemeeterType(b[0])
+	rt.BufferSize = byteOrder.Uint16(b[2:])
+	rt.VoicemeeterVersion = byteOrder.Uint32(b[4:])
+	rt.OptionBits = byteOrder.Uint32(b[8:])
+	rt.SampleRate = byteOrder.Uint32(b[12:])
+	for i := range rt.InputLevels {
+		rt.InputLevels[i] = int16(byteOrder.Uint16(b[rtOffsetInputLevels+2*i:]))
+	}
+	for i := range rt.OutputLevels {
+		rt.OutputLevels[i] = int16(byteOrder.Uint16(b[rtOffsetOutputLevels+2*i:]))
+	}
+	rt.TransportBit = byteOrder.Uint32(b[rtOffsetTransport:])
+	for i := range 8 {
+		rt.StripState[i] = StripMode(byteOrder.Uint32(b[rtOffsetStripState+4*i:]))
+		rt.BusState[i] = StripMode(byteOrder.Uint32(b[rtOffsetBusState+4*i:]))
+		for layer := range 8 {
+			rt.StripGain[layer][i] = int16(byteOrder.Uint16(b[rtOffsetStripGain+16*layer+2*i:]))
+		}
+		rt.BusGain[i] = int16(byteOrder.Uint16(b[rtOffsetBusGain+2*i:]))
+		rt.StripLabel[i] = getPingString(b[rtOffsetStripLabel+rtLabelSize*i : rtOffsetStripLabel+rtLabelSize*(i+1)])
+		rt.BusLabel[i] = getPingString(b[rtOffsetBusLabel+rtLabelSize*i : rtOffsetBusLabel+rtLabelSize*(i+1)])
+	}
+	return nil
+}
+
+// NewRTPacketRegisterPacket builds a registration request asking for RT packets of the given
+// ID to be sent for timeout seconds (0-255).
+func NewRTPacketRegisterPacket(packetID ServiceFunction, timeout uint8) *Packet {
+	h := NewServiceHeader(ServiceRTPacketRegister, packetID, 0)
+	h.SetStreamName(RTPacketRegisterStreamName)
+	h.FormatBit = timeout // The timeout is carried in the FormatBit field
+	return &Packet{Header: h}
+}
+
+// RTPacketClientConfig configures an RTPacketClient.
+type RTPacketClientConfig struct {
+	Addr    *net.UDPAddr  // Voicemeeter address (nil for a dialed Conn)
+	Timeout time.Duration // Registration timeout, 1-255 s (DefaultRTPacketTimeout if zero)
+
+	// OnPacket, if set, is called for every RT packet received.
+	OnPacket func(rt *RTPacket, addr *net.UDPAddr)
+}
+
+// RTPacketClient registers with Voicemeeter for RT packets and decodes the real-time state
+// it sends. Run keeps the registration alive by re-registering at half the timeout.
+//
+// RTPacketClient is a Handler: it must be fed the packets received on its Conn, either by a
+// Mux (registered for ProtocolService) or by calling Serve. It is safe for concurrent use.
+type RTPacketClient struct {
+	conn *Conn
+	cfg  RTPacketClientConfig
+
+	mu       sync.Mutex
+	latest   *RTPacket
+	received time.Time
+}
+
+// NewRTPacketClient creates a client requesting RT packets over conn.
+func NewRTPacketClient(conn *Conn, cfg RTPacketClientConfig) (*RTPacketClient, error) {
+	if conn == nil {
+		return nil, errors.New("connection cannot be nil")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultRTPacketTimeout
+	}
+	if cfg.Timeout < time.Second || cfg.Timeout > 255*time.Second {
+		return nil, fmt.Errorf("registration timeout must be between 1s and 255s, got %v", cfg.Timeout)
+	}
+	return &RTPacketClient{conn: conn, cfg: cfg}, nil
+}
+
+// Register sends a single registration request.
+func (c *RTPacketClient) Register() error {
+	packet := NewRTPacketRegisterPacket(RTPacketIDVoicemeeter, uint8(c.cfg.Timeout/time.Second))
+	if err := c.conn.Send(packet, c.cfg.Addr); err != nil {
+		return fmt.Errorf("failed to send RT packet registration: %w", err)
+	}
+	return nil
+}
+
+// Run registers immediately and then re-registers every half timeout, before the
+// registration expires, until ctx is canceled. Send errors are retried on the next
+// interval. It returns ctx.Err().
+func (c *RTPacketClient) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.cfg.Timeout / 2)
+	defer ticker.Stop()
+	for {
+		_ = c.Register() // Retried on the next tick
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// HandlePacket decodes RT packets. Other packets, and packets from other addresses than
+// the configured one, are ignored.
+func (c *RTPacketClient) HandlePacket(p *Packet, addr *net.UDPAddr) {
+	if p == nil || !p.Header.SubProtocol().IsService() ||
+		p.Header.ServiceType() != ServiceRTPacket ||
+		p.Header.ServiceFunction() != RTPacketIDVoicemeeter {
+		return
+	}
+	if c.cfg.Addr != nil && (addr == nil || !c.cfg.Addr.IP.Equal(addr.IP)) {
+		return
+	}
+	rt := &RTPacket{}
+	if err := rt.UnmarshalBinary(p.Data); err != nil {
+		return
+	}
+	c.mu.Lock()
+	c.latest = rt
+	c.received = time.Now()
+	c.mu.Unlock()
+	if c.cfg.OnPacket != nil {
+		c.cfg.OnPacket(rt, addr)
+	}
+}
+
+// Serve reads packets from the client's Conn until the connection is closed (returning nil)
+// or fails. Use a Mux instead if the Conn also carries other traffic.
+func (c *RTPacketClient) Serve() error {
+	return serve(c.conn, c)
+}
+
+// Latest returns the most recently received RT packet and its arrival time.
+// ok is false if no packet has been received yet.
+func (c *RTPacketClient) Latest() (rt RTPacket, received time.Time, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.latest == nil {
+		return RTPacket{}, time.Time{}, false
+	}
+	return *c.latest, c.received, true
+}
@@ -0,0 +1,87 @@
+package vban
+
+import "fmt"
+
+// --- Serial Protocol Header Fields (Spec p.14-16) ---
+
+// SerialBitMode describes the UART framing carried in the FormatNbs field of Serial packets.
+type SerialBitMode uint8
+
+const (
+	SerialStopBitsMask SerialBitMode = 0x03 // Bits 0-1: number of stop bits
+	SerialStopBits1    SerialBitMode = 0x00 // 1 stop bit
+	SerialStopBits15   SerialBitMode = 0x01 // 1.5 stop bits
+	SerialStopBits2    SerialBitMode = 0x02 // 2 stop bits
+	SerialStartBit     SerialBitMode = 0x04 // Bit 2: start bit used
+	SerialParity       SerialBitMode = 0x08 // Bit 3: parity checking enabled
+	SerialMultipart    SerialBitMode = 0x80 // Bit 7: data continues in the next packet
+)
+
+// StopBits returns the number of stop bits (1, 1.5 or 2).
+// Returns 0 for the undefined value 3.
+func (m SerialBitMode) StopBits() float64 {
+	switch m & SerialStopBitsMask {
+	case SerialStopBits1:
+		return 1
+	case SerialStopBits15:
+		return 1.5
+	case SerialStopBits2:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// HasStartBit reports whether the start bit flag is set.
+func (m SerialBitMode) HasStartBit() bool { return m&SerialStartBit != 0 }
+
+// HasParity reports whether the parity checking flag is set.
+func (m SerialBitMode) HasParity() bool { return m&SerialParity != 0 }
+
+// IsMultipart reports whether the multipart flag is set.
+func (m SerialBitMode) IsMultipart() bool { return m&SerialMultipart != 0 }
+
+// SerialBitMode returns the UART framing from the FormatNbs field (valid for Serial protocol).
+func (h *Header) SerialBitMode() SerialBitMode {
+	return SerialBitMode(h.FormatNbs)
+}
+
+// SetSerialBitMode sets the UART framing in the FormatNbs field (valid for Serial protocol).
+func (h *Header) SetSerialBitMode(mode SerialBitMode) {
+	h.FormatNbs = uint8(mode)
+}
+
+// ChannelIdent returns the channel identifier from the FormatNbc field (valid for Serial and Text protocols).
+func (h *Header) ChannelIdent() uint8 {
+	return h.FormatNbc
+}
+
+// SetChannelIdent sets the channel identifier in the FormatNbc field (valid for Serial and Text protocols).
+func (h *Header) SetChannelIdent(ident uint8) {
+	h.FormatNbc = ident
+}
+
+// BPS returns the bit rate announced by a Serial or Text header, or 0 if undefined.
+func (h *Header) BPS() uint32 {
+	return h.SRIndex().GetRate(h.SubProtocol())
+}
+
+// SetSerialFormat configures FormatSR and FormatBit fields specifically for the Serial protocol.
+// It combines the sub-protocol, BPS index, data type (normally DataTypeUINT8) and serial type.
+// Ensures the reserved bit in FormatBit is cleared.
+func (h *Header) SetSerialFormat(bpsIndex SRIndex, dataType DataType, serialType CodecType) {
+	h.FormatSR = (uint8(ProtocolSerial) & uint8(ProtocolMask)) | (uint8(bpsIndex) & uint8(SRMask))
+	h.FormatBit = (uint8(serialType) & uint8(CodecMask)) | (uint8(dataType) & uint8(DataTypeMask))
+	h.FormatBit &^= 0x08 // Clear reserved bit 3 (mask 0x08)
+}
+
+// FindBPSIndex returns the SRIndex corresponding to the given Serial/Text bit rate.
+// Returns an error if the rate is not defined by the specification.
+func FindBPSIndex(bps uint32) (SRIndex, error) {
+	for index, rate := range BPSList {
+		if rate == bps {
+			return index, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported bit rate for VBAN: %d bps", bps)
+}
@@ -0,0 +1,37 @@
+package vban
+
+// --- Service Protocol Header Fields (Spec p.23) ---
+
+// ServiceStreamName is the stream name used by service packets such as PINGO requests and replies.
+const ServiceStreamName = "VBAN Service"
+
+// IsReply reports whether the reply flag (bit 7) is set.
+func (fn ServiceFunction) IsReply() bool { return fn&ServiceFuncReply != 0 }
+
+// NewServiceHeader creates a Header for the Service protocol with the given service type,
+// function and request ID (stored in NuFrame). The stream name is set to ServiceStreamName.
+func NewServiceHeader(serviceType ServiceType, function ServiceFunction, requestID uint32) Header {
+	h := NewHeader(ProtocolService, ServiceStreamName)
+	h.SetService(serviceType, function)
+	h.NuFrame = requestID
+	return h
+}
+
+// ServiceType returns the service type from the FormatNbc field (valid for Service protocol).
+func (h *Header) ServiceType() ServiceType {
+	return ServiceType(h.FormatNbc)
+}
+
+// ServiceFunction returns the service function from the FormatNbs field (valid for Service protocol).
+func (h *Header) ServiceFunction() ServiceFunction {
+	return ServiceFunction(h.FormatNbs)
+}
+
+// SetService configures the header for the Service protocol with the given service type and function.
+// The SR index and FormatBit are cleared.
+func (h *Header) SetService(serviceType ServiceType, function ServiceFunction) {
+	h.FormatSR = uint8(ProtocolService)
+	h.FormatNbs = uint8(function)
+	h.FormatNbc = uint8(serviceType)
+	h.FormatBit = 0
+}
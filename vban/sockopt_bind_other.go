@@ -0,0 +1,10 @@
+//go:build unix && !linux && !darwin
+
+package vban
+
+import "syscall"
+
+// bindToInterface restricts the socket to the named network interface.
+func bindToInterface(raw syscall.RawConn, network, name string) error {
+	return errSockoptUnsupported
+}
@@ -0,0 +1,23 @@
+//go:build darwin
+
+package vban
+
+import (
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// bindToInterface restricts the socket to the named network interface (IP_BOUND_IF or
+// IPV6_BOUND_IF).
+func bindToInterface(raw syscall.RawConn, network, name string) error {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return err
+	}
+	if network == "udp4" {
+		return setSockoptInt(raw, unix.IPPROTO_IP, unix.IP_BOUND_IF, iface.Index)
+	}
+	return setSockoptInt(raw, unix.IPPROTO_IPV6, unix.IPV6_BOUND_IF, iface.Index)
+}
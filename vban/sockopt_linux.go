@@ -0,0 +1,21 @@
+//go:build linux
+
+package vban
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// bindToInterface restricts the socket to the named network interface (SO_BINDTODEVICE).
+// Linux versions before 5.7 require CAP_NET_RAW.
+func bindToInterface(raw syscall.RawConn, network, name string) error {
+	var sockErr error
+	if err := raw.Control(func(fd uintptr) {
+		sockErr = unix.BindToDevice(int(fd), name)
+	}); err != nil {
+		return err
+	}
+	return sockErr
+}
@@ -0,0 +1,35 @@
+//go:build !unix && !windows
+
+package vban
+
+import "syscall"
+
+// setBroadcast enables or disables sending to broadcast addresses (SO_BROADCAST).
+func setBroadcast(raw syscall.RawConn, on bool) error {
+	return errSockoptUnsupported
+}
+
+// setReadBuffer sets the size of the socket receive buffer (SO_RCVBUF).
+func setReadBuffer(raw syscall.RawConn, bytes int) error {
+	return errSockoptUnsupported
+}
+
+// setWriteBuffer sets the size of the socket send buffer (SO_SNDBUF).
+func setWriteBuffer(raw syscall.RawConn, bytes int) error {
+	return errSockoptUnsupported
+}
+
+// setTrafficClass sets the IPv4 TOS byte or the IPv6 traffic class of sent packets.
+func setTrafficClass(raw syscall.RawConn, network string, tos int) error {
+	return errSockoptUnsupported
+}
+
+// setReusePort allows several sockets to bind the same address and port (SO_REUSEPORT).
+func setReusePort(raw syscall.RawConn) error {
+	return errSockoptUnsupported
+}
+
+// bindToInterface restricts the socket to the named network interface.
+func bindToInterface(raw syscall.RawConn, network, name string) error {
+	return errSockoptUnsupported
+}
@@ -0,0 +1,14 @@
+//go:build unix && !solaris
+
+package vban
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// setReusePort allows several sockets to bind the same address and port (SO_REUSEPORT).
+func setReusePort(raw syscall.RawConn) error {
+	return setSockoptInt(raw, unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+}
@@ -0,0 +1,10 @@
+//go:build solaris
+
+package vban
+
+import "syscall"
+
+// setReusePort allows several sockets to bind the same address and port (SO_REUSEPORT).
+func setReusePort(raw syscall.RawConn) error {
+	return errSockoptUnsupported
+}
@@ -0,0 +1,53 @@
+//go:build unix
+
+package vban
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// setSockoptInt sets an integer socket option on the underlying file descriptor.
+func setSockoptInt(raw syscall.RawConn, level, opt, value int) error {
+	var sockErr error
+	if err := raw.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), level, opt, value)
+	}); err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// setBroadcast enables or disables sending to broadcast addresses (SO_BROADCAST).
+func setBroadcast(raw syscall.RawConn, on bool) error {
+	value := 0
+	if on {
+		value = 1
+	}
+	return setSockoptInt(raw, unix.SOL_SOCKET, unix.SO_BROADCAST, value)
+}
+
+// setReadBuffer sets the size of the socket receive buffer (SO_RCVBUF).
+func setReadBuffer(raw syscall.RawConn, bytes int) error {
+	return setSockoptInt(raw, unix.SOL_SOCKET, unix.SO_RCVBUF, bytes)
+}
+
+// setWriteBuffer sets the size of the socket send buffer (SO_SNDBUF).
+func setWriteBuffer(raw syscall.RawConn, bytes int) error {
+	return setSockoptInt(raw, unix.SOL_SOCKET, unix.SO_SNDBUF, bytes)
+}
+
+// setTrafficClass sets the IPv4 TOS byte (IP_TOS) or the IPv6 traffic class (IPV6_TCLASS)
+// of sent packets. On IPv6 sockets, IP_TOS is also set where supported, for IPv4 packets
+// sent through a dual-stack socket.
+func setTrafficClass(raw syscall.RawConn, network string, tos int) error {
+	if network == "udp4" {
+		return setSockoptInt(raw, unix.IPPROTO_IP, unix.IP_TOS, tos)
+	}
+	if err := setSockoptInt(raw, unix.IPPROTO_IPV6, unix.IPV6_TCLASS, tos); err != nil {
+		return err
+	}
+	_ = setSockoptInt(raw, unix.IPPROTO_IP, unix.IP_TOS, tos) // Not supported on IPv6 sockets everywhere
+	return nil
+}
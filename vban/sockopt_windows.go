@@ -0,0 +1,55 @@
+//go:build windows
+
+package vban
+
+import "syscall"
+
+// setSockoptInt sets an integer socket option on the underlying socket handle.
+func setSockoptInt(raw syscall.RawConn, level, opt, value int) error {
+	var sockErr error
+	if err := raw.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(syscall.Handle(fd), level, opt, value)
+	}); err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// setBroadcast enables or disables sending to broadcast addresses (SO_BROADCAST).
+func setBroadcast(raw syscall.RawConn, on bool) error {
+	value := 0
+	if on {
+		value = 1
+	}
+	return setSockoptInt(raw, syscall.SOL_SOCKET, syscall.SO_BROADCAST, value)
+}
+
+// setReadBuffer sets the size of the socket receive buffer (SO_RCVBUF).
+func setReadBuffer(raw syscall.RawConn, bytes int) error {
+	return setSockoptInt(raw, syscall.SOL_SOCKET, syscall.SO_RCVBUF, bytes)
+}
+
+// setWriteBuffer sets the size of the socket send buffer (SO_SNDBUF).
+func setWriteBuffer(raw syscall.RawConn, bytes int) error {
+	return setSockoptInt(raw, syscall.SOL_SOCKET, syscall.SO_SNDBUF, bytes)
+}
+
+// setTrafficClass sets the IPv4 TOS byte of sent packets (IP_TOS). Windows ignores it
+// unless enabled by policy; IPv6 sockets are not supported.
+func setTrafficClass(raw syscall.RawConn, network string, tos int) error {
+	if network != "udp4" {
+		return errSockoptUnsupported
+	}
+	return setSockoptInt(raw, syscall.IPPROTO_IP, syscall.IP_TOS, tos)
+}
+
+// setReusePort allows several sockets to bind the same address and port. Windows has no
+// SO_REUSEPORT, and its SO_REUSEADDR allows taking over ports in use.
+func setReusePort(raw syscall.RawConn) error {
+	return errSockoptUnsupported
+}
+
+// bindToInterface restricts the socket to the named network interface.
+func bindToInterface(raw syscall.RawConn, network, name string) error {
+	return errSockoptUnsupported
+}
@@ -0,0 +1,293 @@
+package vban
+
+import (
+	"cmp"
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+)
+
+// statsRateWindow is the interval over which StreamStats.ByteRate and PacketRate are measured.
+const statsRateWindow = time.Second
+
+// statsResyncFrames is the frame counter jump beyond which a stream is assumed to have
+// restarted rather than delivered a very late packet or lost packets. For audio streams,
+// larger forward jumps are counted as losses if the time since the previous packet
+// accounts for them.
+const statsResyncFrames = 1024
+
+// StreamKey identifies a stream tracked by a StatsCollector.
+type StreamKey struct {
+	Protocol   SubProtocol    // Sub-protocol, without the reserved bits
+	StreamName string         // Stream name
+	Source     netip.AddrPort // Sender address
+}
+
+// StreamStats holds the statistics of one stream. Frame counters are derived from NuFrame,
+// which counts packets; they are not tracked for service packets.
+type StreamStats struct {
+	StreamKey
+
+	Packets    uint64  // Packets received
+	Bytes      uint64  // Bytes received, including headers
+	ByteRate   float64 // Bytes per second over the last complete second (0 once the stream stops)
+	PacketRate float64 // Packets per second over the last complete second (0 once the stream stops)
+
+	Lost       uint64 // Frames skipped by the counter and not received later
+	Duplicates uint64 // Packets received more than once
+	OutOfOrder uint64 // Packets arriving after a later frame
+	Restarts   uint64 // Counter jumps, e.g. because the sender restarted
+
+	// Jitter is the smoothed variation of the packet inter-arrival time from the audio
+	// duration of the packets, as defined for RTP (RFC 3550). It is only computed for
+	// audio streams.
+	Jitter time.Duration
+
+	Header        Header // Header of the latest packet, for its format fields
+	FormatChanges uint64 // Changes of the sample rate, data type, codec or channel count
+
+	FirstSeen time.Time // Time of the first packet
+	LastSeen  time.Time // Time of the latest packet
+}
+
+// LossRatio returns the fraction of frames lost, between 0 and 1.
+func (s *StreamStats) LossRatio() float64 {
+	if total := s.Packets - s.Duplicates + s.Lost; total > 0 {
+		return float64(s.Lost) / float64(total)
+	}
+	return 0
+}
+
+// streamState is the tracking state of one stream.
+type streamState struct {
+	stats StreamStats
+
+	first  uint32 // NuFrame of the first packet since the stream (re)started
+	next   uint32 // Expected NuFrame of the next packet
+	recent uint64 // Bit i is set if frame next-1-i was received
+
+	lastArrival time.Time // Arrival time of the newest in-order packet
+	jitter      float64   // Smoothed jitter in seconds
+
+	windowStart   time.Time // Start of the current rate window
+	windowBytes   uint64
+	windowPackets uint64
+}
+
+// streamID is the map key of a stream. Unlike StreamKey, it can be built from a header
+// without allocating.
+type streamID struct {
+	proto  SubProtocol
+	name   [MaxStreamNameLen]byte
+	source netip.AddrPort
+}
+
+// StatsCollector tracks the health of the streams received on one or more Conns, per
+// sub-protocol, stream name and sender: packet and byte rates, lost, duplicate and
+// out-of-order frames, jitter, format changes and the time each stream was last seen.
+//
+// Attach it to a Conn with SetObserver to count every packet received, or feed it packets
+// through HandlePacket (e.g. from a Mux). Query it with Snapshot or Stream; both return
+// copies. StatsCollector is safe for concurrent use.
+type StatsCollector struct {
+	mu      sync.Mutex
+	streams map[streamID]*streamState
+}
+
+// NewStatsCollector creates an empty StatsCollector.
+func NewStatsCollector() *StatsCollector {
+	return &StatsCollector{streams: make(map[streamID]*streamState)}
+}
+
+// HandlePacket records a received packet.
+func (c *StatsCollector) HandlePacket(p *Packet, addr *net.UDPAddr) {
+	if p == nil {
+		return
+	}
+	var src netip.AddrPort
+	if addr != nil {
+		src = addr.AddrPort()
+		src = netip.AddrPortFrom(src.Addr().Unmap(), src.Port())
+	}
+	c.ObservePacket(p.Header, HeaderSize+len(p.Data), src)
+}
+
+// ObservePacket records a received packet. It implements PacketObserver.
+func (c *StatsCollector) ObservePacket(h Header, size int, addr netip.AddrPort) {
+	now := time.Now()
+	proto := h.SubProtocol() & ProtocolMask
+	id := streamID{proto: proto, name: h.StreamName, source: addr}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st, ok := c.streams[id]
+	if !ok {
+		key := StreamKey{Protocol: proto, StreamName: h.GetStreamName(), Source: addr}
+		st = &streamState{
+			stats:       StreamStats{StreamKey: key, FirstSeen: now, Header: h},
+			first:       h.NuFrame,
+			next:        h.NuFrame,
+			windowStart: now,
+		}
+		c.streams[id] = st
+	}
+	s := &st.stats
+	s.Packets++
+	s.Bytes += uint64(size)
+	s.LastSeen = now
+	if formatChanged(&s.Header, &h) {
+		s.FormatChanges++
+	}
+	s.Header = h
+
+	// Packet and byte rates over complete windows.
+	st.windowBytes += uint64(size)
+	st.windowPackets++
+	if elapsed := now.Sub(st.windowStart); elapsed >= statsRateWindow {
+		s.ByteRate = float64(st.windowBytes) / elapsed.Seconds()
+		s.PacketRate = float64(st.windowPackets) / elapsed.Seconds()
+		st.windowStart, st.windowBytes, st.windowPackets = now, 0, 0
+	}
+
+	if !proto.IsService() { // Service packets carry request IDs instead of frame counters
+		st.track(&h, now)
+	}
+}
+
+// track updates the frame counter statistics and the jitter with a packet that arrived at now.
+func (st *streamState) track(h *Header, now time.Time) {
+	s := &st.stats
+	d := int32(h.NuFrame - st.next)
+	switch {
+	case d < -statsResyncFrames || d > st.maxJump(h, now):
+		s.Restarts++
+		st.first = h.NuFrame
+		st.recent = 1
+		st.next = h.NuFrame + 1
+		st.lastArrival = now
+	case d >= 0:
+		// In order, possibly after a gap.
+		s.Lost += uint64(d)
+		if !st.lastArrival.IsZero() && s.Protocol.IsAudio() {
+			st.updateJitter(h, now, int(d)+1)
+		}
+		st.recent = st.recent<<(d+1) | 1
+		st.next = h.NuFrame + 1
+		st.lastArrival = now
+	default:
+		// Behind the newest frame: a duplicate or a late packet.
+		i := -d - 1
+		if i < 64 && st.recent&(1<<i) != 0 {
+			s.Duplicates++
+			return
+		}
+		s.OutOfOrder++
+		if i < 64 {
+			st.recent |= 1 << i
+		}
+		if int32(h.NuFrame-st.first) >= 0 && s.Lost > 0 {
+			s.Lost-- // Counted as lost when the gap was seen
+		}
+	}
+}
+
+// maxJump returns the largest forward frame counter jump counted as lost packets rather
+// than a restart: statsResyncFrames, or twice the number of audio packets that fit in the
+// time since the previous packet.
+func (st *streamState) maxJump(h *Header, now time.Time) int32 {
+	jump := int64(statsResyncFrames)
+	if rate := h.SRIndex().GetRate(ProtocolAudio); st.stats.Protocol.IsAudio() && rate > 0 && !st.lastArrival.IsZero() {
+		packets := now.Sub(st.lastArrival).Seconds() * float64(rate) / float64(int(h.FormatNbs)+1)
+		jump = max(jump, 2*int64(packets))
+	}
+	return int32(min(jump, 1<<30))
+}
+
+// updateJitter updates the jitter with an audio packet arriving frames packets after the
+// previous in-order packet.
+func (st *streamState) updateJitter(h *Header, now time.Time, frames int) {
+	rate := h.SRIndex().GetRate(ProtocolAudio)
+	if rate == 0 {
+		return
+	}
+	expected := float64(frames*(int(h.FormatNbs)+1)) / float64(rate)
+	deviation := now.Sub(st.lastArrival).Seconds() - expected
+	if deviation < 0 {
+		deviation = -deviation
+	}
+	st.jitter += (deviation - st.jitter) / 16
+	st.stats.Jitter = time.Duration(st.jitter * float64(time.Second))
+}
+
+// formatChanged reports whether the sample rate, data type, codec or channel count differs
+// between two headers of the same stream.
+func formatChanged(a, b *Header) bool {
+	return a.FormatSR != b.FormatSR || a.FormatBit != b.FormatBit || a.FormatNbc != b.FormatNbc
+}
+
+// snapshot returns a copy of the stream's statistics as of now.
+func (st *streamState) snapshot(now time.Time) StreamStats {
+	s := st.stats
+	if now.Sub(st.windowStart) >= 2*statsRateWindow {
+		s.ByteRate, s.PacketRate = 0, 0 // No packet completed a window recently
+	}
+	return s
+}
+
+// Snapshot returns the statistics of all streams, sorted by stream name, sub-protocol and
+// source address.
+func (c *StatsCollector) Snapshot() []StreamStats {
+	now := time.Now()
+	c.mu.Lock()
+	stats := make([]StreamStats, 0, len(c.streams))
+	for _, st := range c.streams {
+		stats = append(stats, st.snapshot(now))
+	}
+	c.mu.Unlock()
+	slices.SortFunc(stats, func(a, b StreamStats) int {
+		return cmp.Or(
+			cmp.Compare(a.StreamName, b.StreamName),
+			cmp.Compare(a.Protocol, b.Protocol),
+			a.Source.Compare(b.Source),
+		)
+	})
+	return stats
+}
+
+// Stream returns the statistics of one stream, and false if no packet of it was received.
+func (c *StatsCollector) Stream(key StreamKey) (StreamStats, bool) {
+	var h Header
+	h.SetStreamName(key.StreamName)
+	id := streamID{proto: key.Protocol & ProtocolMask, name: h.StreamName, source: key.Source}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st, ok := c.streams[id]
+	if !ok {
+		return StreamStats{}, false
+	}
+	return st.snapshot(time.Now()), true
+}
+
+// Prune forgets the streams not seen since before and returns how many were removed.
+func (c *StatsCollector) Prune(before time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for id, st := range c.streams {
+		if st.stats.LastSeen.Before(before) {
+			delete(c.streams, id)
+			n++
+		}
+	}
+	return n
+}
+
+// Reset forgets all streams.
+func (c *StatsCollector) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.streams)
+}
@@ -0,0 +1,178 @@
+package vban
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// --- Text Protocol (Spec p.19-21) ---
+
+// SetTextFormat configures FormatSR and FormatBit fields specifically for the Text protocol.
+// It combines the sub-protocol, BPS index, data type (DataTypeUINT8) and text format
+// (TextASCII, TextUTF8 or TextWCHAR). Ensures the reserved bit in FormatBit is cleared.
+func (h *Header) SetTextFormat(bpsIndex SRIndex, format CodecType) {
+	h.FormatSR = (uint8(ProtocolText) & uint8(ProtocolMask)) | (uint8(bpsIndex) & uint8(SRMask))
+	h.FormatBit = (uint8(format) & uint8(CodecMask)) | uint8(DataTypeUINT8)
+	h.FormatBit &^= 0x08 // Clear reserved bit 3 (mask 0x08)
+}
+
+// EncodeText converts s into the payload representation of the given text format.
+// TextWCHAR is encoded as UTF-16 little endian. No null terminator is appended.
+// TextASCII fails if s contains non-ASCII characters.
+func EncodeText(format CodecType, s string) ([]byte, error) {
+	switch format & CodecMask {
+	case TextASCII:
+		for i := 0; i < len(s); i++ {
+			if s[i] >= utf8.RuneSelf {
+				return nil, fmt.Errorf("non-ASCII character at byte %d", i)
+			}
+		}
+		return []byte(s), nil
+	case TextUTF8:
+		if !utf8.ValidString(s) {
+			return nil, errors.New("string is not valid UTF-8")
+		}
+		return []byte(s), nil
+	case TextWCHAR:
+		units := utf16.Encode([]rune(s))
+		data := make([]byte, 2*len(units))
+		for i, u := range units {
+			byteOrder.PutUint16(data[2*i:], u)
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("unsupported text format: 0x%02X", uint8(format))
+	}
+}
+
+// DecodeText converts a Text payload of the given format into a Go string.
+// Trailing null terminators are removed.
+func DecodeText(format CodecType, data []byte) (string, error) {
+	switch format & CodecMask {
+	case TextASCII, TextUTF8:
+		for len(data) > 0 && data[len(data)-1] == 0 {
+			data = data[:len(data)-1]
+		}
+		if format&CodecMask == TextASCII {
+			for i, b := range data {
+				if b >= utf8.RuneSelf {
+					return "", fmt.Errorf("non-ASCII byte 0x%02X at offset %d", b, i)
+				}
+			}
+		} else if !utf8.Valid(data) {
+			return "", errors.New("payload is not valid UTF-8")
+		}
+		return string(data), nil
+	case TextWCHAR:
+		if len(data)%2 != 0 {
+			return "", fmt.Errorf("WCHAR payload has odd length %d", len(data))
+		}
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = byteOrder.Uint16(data[2*i:])
+		}
+		for len(units) > 0 && units[len(units)-1] == 0 {
+			units = units[:len(units)-1]
+		}
+		return string(utf16.Decode(units)), nil
+	default:
+		return "", fmt.Errorf("unsupported text format: 0x%02X", uint8(format))
+	}
+}
+
+// Text decodes the payload of a Text packet according to the format in its header.
+func (p *Packet) Text() (string, error) {
+	if !p.Header.SubProtocol().IsText() {
+		return "", errors.New("packet is not a text packet")
+	}
+	return DecodeText(p.Header.CodecType(), p.Data)
+}
+
+// textChunkLen returns the length of the longest prefix of data that fits in one packet
+// without splitting a UTF-8 sequence or a UTF-16 surrogate pair.
+func textChunkLen(format CodecType, data []byte) int {
+	n := len(data)
+	if n <= MaxPacketDataSize {
+		return n
+	}
+	n = MaxPacketDataSize
+	switch format & CodecMask {
+	case TextUTF8:
+		for n > 0 && !utf8.RuneStart(data[n]) {
+			n--
+		}
+	case TextWCHAR:
+		n -= n % 2
+		if u := byteOrder.Uint16(data[n-2:]); u >= 0xD800 && u < 0xDC00 {
+			n -= 2 // Keep a high surrogate together with its low surrogate
+		}
+	}
+	return n
+}
+
+// EncodeTextPackets encodes s according to the text format of header (see SetTextFormat) and
+// splits it into packets of at most MaxPacketDataSize bytes, never splitting a character.
+// The first packet uses header.NuFrame, and each subsequent packet increments it.
+// An empty string yields no packets.
+func EncodeTextPackets(header Header, s string) ([]*Packet, error) {
+	format := header.CodecType()
+	data, err := EncodeText(format, s)
+	if err != nil {
+		return nil, err
+	}
+	var packets []*Packet
+	for len(data) > 0 {
+		n := textChunkLen(format, data)
+		h := header
+		h.NuFrame = header.NuFrame + uint32(len(packets))
+		packets = append(packets, &Packet{Header: h, Data: data[:n]})
+		data = data[n:]
+	}
+	return packets, nil
+}
+
+// TextWriter sends strings as a VBAN Text stream, e.g. remote commands such as
+// "Strip[0].Gain = -6;" for Voicemeeter. It is not safe for concurrent use.
+type TextWriter struct {
+	conn   *Conn
+	addr   *net.UDPAddr
+	header Header
+}
+
+// NewTextWriter creates a writer for the text stream streamName using the given format
+// (TextASCII, TextUTF8 or TextWCHAR). If conn was created using Dial, addr can be nil.
+func NewTextWriter(conn *Conn, addr *net.UDPAddr, streamName string, format CodecType) (*TextWriter, error) {
+	if conn == nil {
+		return nil, errors.New("connection cannot be nil")
+	}
+	switch format & CodecMask {
+	case TextASCII, TextUTF8, TextWCHAR:
+	default:
+		return nil, fmt.Errorf("unsupported text format: 0x%02X", uint8(format))
+	}
+	h := NewHeader(ProtocolText, streamName)
+	h.SetTextFormat(0, format)
+	return &TextWriter{conn: conn, addr: addr, header: h}, nil
+}
+
+// Header returns a pointer to the template header, allowing fields such as the
+// BPS index or channel identifier to be adjusted before sending.
+func (w *TextWriter) Header() *Header { return &w.header }
+
+// Send sends s, split across as many packets as needed.
+func (w *TextWriter) Send(s string) error {
+	packets, err := EncodeTextPackets(w.header, s)
+	if err != nil {
+		return err
+	}
+	for _, packet := range packets {
+		if err := w.conn.Send(packet, w.addr); err != nil {
+			return fmt.Errorf("failed to send text packet %d: %w", packet.Header.NuFrame, err)
+		}
+		w.header.NuFrame++
+	}
+	return nil
+}
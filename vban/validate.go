@@ -0,0 +1,95 @@
+package vban
+
+import "fmt"
+
+// --- Protocol Validation ---
+
+// Highest SR/BPS indexes defined by Spec Rev 11.
+const (
+	maxAudioSRIndex  SRIndex = 20 // 705600 Hz
+	maxSerialSRIndex SRIndex = 24 // 3000000 bps
+)
+
+// CodecUser is the codec/type/format value reserved for user-defined formats in all
+// sub-protocols (Spec p.10, p.16, p.20).
+const CodecUser CodecType = 0xF0
+
+// ValidationError describes a violation of the VBAN protocol rules found by Header.Validate
+// or Packet.Validate. It matches ErrInvalid with errors.Is.
+type ValidationError struct {
+	Field  string // Offending header field ("VBAN", "FormatSR", "FormatBit") or "Data"
+	Value  int    // Offending value (field value or payload length)
+	Reason string // Human-readable description of the rule that was violated
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s (%d): %s", e.Field, e.Value, e.Reason)
+}
+
+// Is reports whether target is ErrInvalid.
+func (e *ValidationError) Is(target error) bool { return target == ErrInvalid }
+
+// Validate checks the header against the protocol rules of its sub-protocol:
+// the magic number, defined sub-protocols (0x80-0xE0 are undefined), defined SR/BPS
+// indexes, the reserved bit 3 of FormatBit, and defined codecs, serial types and text
+// formats (CodecUser is accepted). It returns the first violation as a *ValidationError.
+func (h *Header) Validate() error {
+	if h.VBAN != HeaderMagic {
+		return &ValidationError{Field: "VBAN", Value: int(h.VBAN), Reason: "bad magic number"}
+	}
+	sp := h.SubProtocol()
+	if sp == ProtocolService {
+		return nil // FormatNbs, FormatNbc and FormatBit carry service-specific values
+	}
+	var maxIndex SRIndex
+	switch sp {
+	case ProtocolAudio:
+		maxIndex = maxAudioSRIndex
+	case ProtocolSerial, ProtocolText:
+		maxIndex = maxSerialSRIndex
+	default:
+		return &ValidationError{Field: "FormatSR", Value: int(sp), Reason: "undefined sub-protocol"}
+	}
+	if index := h.SRIndex(); index > maxIndex {
+		return &ValidationError{Field: "FormatSR", Value: int(index), Reason: fmt.Sprintf("undefined SR/BPS index (max %d)", maxIndex)}
+	}
+	if h.FormatBit&0x08 != 0 {
+		return &ValidationError{Field: "FormatBit", Value: int(h.FormatBit), Reason: "reserved bit 3 is set"}
+	}
+	if sp != ProtocolAudio && h.DataType() != DataTypeUINT8 {
+		return &ValidationError{Field: "FormatBit", Value: int(h.DataType()), Reason: "serial and text data type must be UINT8"}
+	}
+	codec := h.CodecType()
+	var defined bool
+	switch sp {
+	case ProtocolAudio:
+		defined = codec == CodecPCM || codec == CodecVBCA || codec == CodecVBCV
+	case ProtocolSerial:
+		defined = codec == SerialGeneric || codec == SerialMIDI
+	case ProtocolText:
+		defined = codec == TextASCII || codec == TextUTF8 || codec == TextWCHAR
+	}
+	if !defined && codec != CodecUser {
+		return &ValidationError{Field: "FormatBit", Value: int(codec), Reason: "undefined codec, serial type or text format"}
+	}
+	return nil
+}
+
+// Validate checks the header (see Header.Validate) and the payload length. The payload
+// must not exceed MaxPacketDataSize and, for PCM audio, must be exactly
+// SamplesPerFrame * Channels samples of the data type.
+func (p *Packet) Validate() error {
+	if err := p.Header.Validate(); err != nil {
+		return err
+	}
+	if len(p.Data) > MaxPacketDataSize {
+		return &ValidationError{Field: "Data", Value: len(p.Data), Reason: fmt.Sprintf("payload exceeds %d bytes", MaxPacketDataSize)}
+	}
+	if p.Header.SubProtocol() == ProtocolAudio && p.Header.CodecType() == CodecPCM {
+		if size := p.Header.DataType().PayloadSize(p.AudioSamples()); len(p.Data) != size {
+			return &ValidationError{Field: "Data", Value: len(p.Data), Reason: fmt.Sprintf("PCM payload must be %d bytes", size)}
+		}
+	}
+	return nil
+}
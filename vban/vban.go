@@ -1,9 +1,15 @@
 package vban
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"net"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Packet represents a complete VBAN packet, including its header and data payload.
@@ -16,7 +22,7 @@ type Packet struct {
 // It returns an error if the data payload size exceeds the maximum allowed limit.
 func NewPacket(header Header, data []byte) (*Packet, error) {
 	if len(data) > MaxPacketDataSize {
-		return nil, fmt.Errorf("data size (%d bytes) exceeds VBAN maximum (%d bytes)", len(data), MaxPacketDataSize)
+		return nil, fmt.Errorf("%w: data size (%d bytes) exceeds VBAN maximum (%d bytes)", ErrOversized, len(data), MaxPacketDataSize)
 	}
 	// Note: The data slice is assigned directly. If the caller modifies the slice
 	// after calling NewPacket, the Packet's Data field will reflect the change.
@@ -34,52 +40,61 @@ func NewPacket(header Header, data []byte) (*Packet, error) {
 // MarshalBinary converts the entire VBAN packet (Header + Data) into a single byte slice
 // suitable for sending over UDP.
 func (p *Packet) MarshalBinary() ([]byte, error) {
-	headerBytes, err := p.Header.MarshalBinary()
+	return p.AppendBinary(make([]byte, 0, HeaderSize+len(p.Data)))
+}
+
+// AppendBinary appends the wire representation of the packet (Header + Data) to b.
+// It does not allocate if b has enough spare capacity.
+func (p *Packet) AppendBinary(b []byte) ([]byte, error) {
+	// Validate data size before concatenating
+	if len(p.Data) > MaxPacketDataSize {
+		return nil, fmt.Errorf("%w: data size (%d bytes) exceeds VBAN maximum (%d bytes)", ErrOversized, len(p.Data), MaxPacketDataSize)
+	}
+	b, err := p.Header.AppendBinary(b)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal packet header: %w", err)
 	}
-	// Re-validate data size before concatenating
-	if len(p.Data) > MaxPacketDataSize {
-		return nil, fmt.Errorf("data size (%d bytes) exceeds VBAN maximum (%d bytes)", len(p.Data), MaxPacketDataSize)
-	}
-
-	// Allocate buffer for header + data and concatenate
-	fullPacket := make([]byte, 0, len(headerBytes)+len(p.Data))
-	fullPacket = append(fullPacket, headerBytes...)
-	fullPacket = append(fullPacket, p.Data...)
-	return fullPacket, nil
+	return append(b, p.Data...), nil
 }
 
-// UnmarshalBinary parses a byte slice representing a full VBAN packet into a Packet struct.
-// It assumes the input `data` contains one complete VBAN packet.
-// It copies the data payload to prevent issues with buffer reuse.
-func UnmarshalBinary(data []byte) (*Packet, error) {
-	if len(data) < HeaderSize {
-		return nil, fmt.Errorf("insufficient data for VBAN packet: got %d bytes, need at least %d", len(data), HeaderSize)
-	}
-	// Packet size cannot exceed the maximum defined size
-	if len(data) > MaxVBANPacketSize {
-		// This check might be redundant if the read buffer is already limited,
-		// but provides an explicit validation against the protocol limit.
-		return nil, fmt.Errorf("packet size (%d bytes) exceeds VBAN maximum (%d bytes)", len(data), MaxVBANPacketSize)
+// UnmarshalBinary parses a byte slice representing a full VBAN packet into p.
+// The payload is copied into p.Data, reusing its capacity, so a Packet that is
+// unmarshaled repeatedly stops allocating once Data has grown to the largest payload.
+// The packet is left unchanged on error.
+func (p *Packet) UnmarshalBinary(data []byte) error {
+	if err := checkPacketSize(len(data)); err != nil {
+		return err
 	}
-
-	p := &Packet{}
 	// First, unmarshal the header part
-	err := p.Header.UnmarshalBinary(data[:HeaderSize]) // Pass only the header bytes
-	if err != nil {
+	if err := p.Header.UnmarshalBinary(data[:HeaderSize]); err != nil {
 		// Error during header parsing (e.g., bad magic number)
-		return nil, fmt.Errorf("failed to unmarshal VBAN header: %w", err)
+		return fmt.Errorf("failed to unmarshal VBAN header: %w", err)
 	}
-
 	// The rest of the data is the payload.
-	// Create a copy of the data payload to ensure the Packet owns its data.
-	dataPayload := data[HeaderSize:]
-	p.Data = make([]byte, len(dataPayload))
-	copy(p.Data, dataPayload)
+	p.Data = append(p.Data[:0], data[HeaderSize:]...)
+	return nil
+}
 
-	// v0.0.1: No validation of Data length against header fields yet.
+// checkPacketSize checks the length of a full VBAN packet against the protocol limits.
+func checkPacketSize(n int) error {
+	if n < HeaderSize {
+		return fmt.Errorf("%w: insufficient data for VBAN packet: got %d bytes, need at least %d", ErrShortPacket, n, HeaderSize)
+	}
+	// Packet size cannot exceed the maximum defined size
+	if n > MaxVBANPacketSize {
+		return fmt.Errorf("%w: packet size (%d bytes) exceeds VBAN maximum (%d bytes)", ErrOversized, n, MaxVBANPacketSize)
+	}
+	return nil
+}
 
+// UnmarshalBinary parses a byte slice representing a full VBAN packet into a new Packet.
+// It assumes the input `data` contains one complete VBAN packet.
+// It copies the data payload to prevent issues with buffer reuse.
+func UnmarshalBinary(data []byte) (*Packet, error) {
+	p := &Packet{}
+	if err := p.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
 	return p, nil
 }
 
@@ -87,15 +102,32 @@ func UnmarshalBinary(data []byte) (*Packet, error) {
 
 // Conn provides methods for sending and receiving VBAN packets over UDP.
 type Conn struct {
-	udpConn *net.UDPConn
+	udpConn *net.UDPConn // Never changes after construction; see closed
+	closed  atomic.Bool  // Set by Close
 	// readBuffer is allocated once per connection to minimize allocations during receive operations.
 	readBuffer []byte
+
+	// Deadlines set by the user, restored after ReceiveContext and SendContext return.
+	mu            sync.Mutex
+	readDeadline  time.Time
+	writeDeadline time.Time
+
+	// strict enables validation of received packets (see SetStrict).
+	strict atomic.Bool
+
+	// observer is notified of every packet received (see SetObserver).
+	observer atomic.Pointer[PacketObserver]
+
+	// Buffers and platform-specific state of SendBatch and ReceiveBatch.
+	recv  recvBatch
+	batch batchState
 }
 
 // Listen creates a VBAN Conn that listens for incoming UDP packets
 // on the specified local address and port.
 // If localAddr is nil, it listens on all available interfaces using the DefaultPort.
-func Listen(localAddr *net.UDPAddr) (*Conn, error) {
+// Options such as WithReadBuffer tune the socket.
+func Listen(localAddr *net.UDPAddr, opts ...Option) (*Conn, error) {
 	addr := localAddr
 	// Default address if nil
 	if addr == nil {
@@ -106,32 +138,41 @@ func Listen(localAddr *net.UDPAddr) (*Conn, error) {
 		addr.Port = DefaultPort
 	}
 
-	conn, err := net.ListenUDP("udp", addr)
+	conn, err := listenUDP("udp", addr, newOptions(opts))
 	if err != nil {
 		return nil, fmt.Errorf("failed to listen on UDP %s: %w", addr.String(), err)
 	}
-	return &Conn{
-		udpConn: conn,
-		// Allocate buffer slightly larger than max packet size to detect overflow.
-		readBuffer: make([]byte, MaxVBANPacketSize+1),
-	}, nil
+	return NewConn(conn), nil
+}
+
+// listenUDP is like net.ListenUDP but applies the socket options before binding.
+func listenUDP(network string, addr *net.UDPAddr, o *options) (*net.UDPConn, error) {
+	lc := net.ListenConfig{Control: o.control}
+	conn, err := lc.ListenPacket(context.Background(), network, addr.String())
+	if err != nil {
+		return nil, err
+	}
+	return conn.(*net.UDPConn), nil
 }
 
 // Dial creates a VBAN Conn configured to send packets to a specific remote UDP address.
 // It can also receive packets (typically replies) from any source on the bound local port.
 // If localAddr is nil, the OS chooses the source IP and port.
-func Dial(localAddr, remoteAddr *net.UDPAddr) (*Conn, error) {
+// remoteAddr may be a multicast group; see SetMulticastTTL, SetMulticastLoopback and
+// SetMulticastInterface for the sender options. Options such as WithDSCP tune the socket.
+func Dial(localAddr, remoteAddr *net.UDPAddr, opts ...Option) (*Conn, error) {
 	if remoteAddr == nil {
 		return nil, errors.New("remote address cannot be nil for Dial")
 	}
-	conn, err := net.DialUDP("udp", localAddr, remoteAddr)
+	dialer := net.Dialer{Control: newOptions(opts).control}
+	if localAddr != nil {
+		dialer.LocalAddr = localAddr
+	}
+	conn, err := dialer.DialContext(context.Background(), "udp", remoteAddr.String())
 	if err != nil {
 		return nil, fmt.Errorf("failed to dial UDP from %v to %s: %w", localAddr, remoteAddr.String(), err)
 	}
-	return &Conn{
-		udpConn:    conn,
-		readBuffer: make([]byte, MaxVBANPacketSize+1),
-	}, nil
+	return NewConn(conn.(*net.UDPConn)), nil
 }
 
 // NewConn wraps an existing *net.UDPConn into a vban.Conn.
@@ -149,11 +190,28 @@ func NewConn(udpConn *net.UDPConn) *Conn {
 // Close closes the underlying UDP connection.
 // It's safe to call Close multiple times.
 func (c *Conn) Close() error {
-	if c.udpConn == nil {
+	if c.udpConn == nil || c.closed.Swap(true) {
 		return nil // Already closed or not initialized
 	}
-	err := c.udpConn.Close()
-	c.udpConn = nil // Mark as closed
+	return c.udpConn.Close()
+}
+
+// conn returns the underlying UDP connection, or ErrClosed if the Conn has been closed.
+// The connection itself is never cleared, so operations racing with Close fail with an
+// error from the socket rather than a data race.
+func (c *Conn) conn() (*net.UDPConn, error) {
+	if c.udpConn == nil || c.closed.Load() {
+		return nil, ErrClosed
+	}
+	return c.udpConn, nil
+}
+
+// closedError makes an error caused by closing the connection during an operation match
+// ErrClosed.
+func closedError(err error) error {
+	if errors.Is(err, net.ErrClosed) && !errors.Is(err, ErrClosed) {
+		return fmt.Errorf("%w: %w", ErrClosed, err)
+	}
 	return err
 }
 
@@ -161,8 +219,9 @@ func (c *Conn) Close() error {
 // If the Conn was created using Dial, `addr` can be nil to send to the dialed address.
 // Otherwise, `addr` must specify the destination UDP address.
 func (c *Conn) Send(packet *Packet, addr *net.UDPAddr) error {
-	if c.udpConn == nil {
-		return errors.New("connection is closed")
+	udpConn, err := c.conn()
+	if err != nil {
+		return err
 	}
 	if packet == nil {
 		return errors.New("cannot send a nil packet")
@@ -177,21 +236,21 @@ func (c *Conn) Send(packet *Packet, addr *net.UDPAddr) error {
 	var n int
 	if addr != nil {
 		// Send to a specific address using WriteToUDP
-		n, err = c.udpConn.WriteToUDP(packetBytes, addr)
+		n, err = udpConn.WriteToUDP(packetBytes, addr)
 	} else {
 		// If addr is nil, assume sending to the dialed address (or fail if not dialed)
-		if c.udpConn.RemoteAddr() == nil {
+		if udpConn.RemoteAddr() == nil {
 			// Not a dialed connection, and no destination address provided
 			return errors.New("destination address (addr) must be provided for non-dialed connections")
 		}
 		// Use Write() for dialed connections
-		n, err = c.udpConn.Write(packetBytes)
+		n, err = udpConn.Write(packetBytes)
 	}
 
 	// Check for UDP write errors
 	if err != nil {
 		// Consider specific error handling, e.g., for network issues
-		return fmt.Errorf("UDP write error: %w", err)
+		return fmt.Errorf("UDP write error: %w", closedError(err))
 	}
 	// Check if the entire packet was written
 	if n != len(packetBytes) {
@@ -202,60 +261,308 @@ func (c *Conn) Send(packet *Packet, addr *net.UDPAddr) error {
 
 // Receive blocks until a UDP packet is received, attempts to parse it as a VBAN packet,
 // and returns the parsed Packet, the sender's address, and any error encountered.
+// If data was received but is not a valid VBAN packet, the sender's address is returned
+// together with an error matching ErrBadMagic, ErrShortPacket or ErrOversized.
+// After Close, the error matches ErrClosed.
 func (c *Conn) Receive() (*Packet, *net.UDPAddr, error) {
-	if c.udpConn == nil {
-		return nil, nil, errors.New("connection is closed")
+	n, addrPort, err := c.read(c.readBuffer)
+	var remoteAddr *net.UDPAddr
+	if addrPort.IsValid() {
+		remoteAddr = net.UDPAddrFromAddrPort(addrPort)
+	}
+	if err != nil {
+		return nil, remoteAddr, err
 	}
 
-	// Read data from the UDP connection into the reusable buffer
-	// ReadFromUDP waits for a packet.
-	n, remoteAddr, err := c.udpConn.ReadFromUDP(c.readBuffer)
+	// Attempt to decode the received bytes into a VBAN Packet struct
+	// Pass only the slice containing the actual received data ([:n]).
+	view, err := c.decode(c.readBuffer[:n], addrPort)
+	if err != nil {
+		// Data was received, but it wasn't a valid VBAN packet (e.g., bad magic number)
+		return nil, remoteAddr, err
+	}
 
-	// Handle read errors
+	// Successfully received and parsed a VBAN packet.
+	// Copy the payload so that the Packet owns its data.
+	packet := &Packet{Header: view.Header, Data: bytes.Clone(view.Data)}
+	return packet, remoteAddr, nil
+}
+
+// ReceiveInto is like Receive but decodes the packet into p, reusing the capacity of
+// p.Data, and returns the sender's address as a netip.AddrPort. Once p.Data has grown to
+// the largest payload received, ReceiveInto does not allocate. On error, p is unchanged
+// and the sender's address is valid if data was received.
+func (c *Conn) ReceiveInto(p *Packet) (netip.AddrPort, error) {
+	n, addr, err := c.read(c.readBuffer)
+	if err != nil {
+		return addr, err
+	}
+	view, err := c.decode(c.readBuffer[:n], addr)
+	if err != nil {
+		return addr, err
+	}
+	p.Header = view.Header
+	p.Data = append(p.Data[:0], view.Data...)
+	return addr, nil
+}
+
+// ReadPacket reads one datagram into buf and returns the packet it contains, without
+// copying: the Data of the returned Packet aliases buf and is only valid until buf is
+// reused. buf must be at least MaxVBANPacketSize+1 bytes long so that oversized
+// datagrams are detected. ReadPacket does not allocate on success.
+func (c *Conn) ReadPacket(buf []byte) (Packet, netip.AddrPort, error) {
+	if _, err := c.conn(); err != nil {
+		return Packet{}, netip.AddrPort{}, err
+	}
+	if len(buf) < MaxVBANPacketSize+1 {
+		return Packet{}, netip.AddrPort{}, fmt.Errorf("buffer too small: got %d bytes, need at least %d", len(buf), MaxVBANPacketSize+1)
+	}
+	n, addr, err := c.read(buf)
+	if err != nil {
+		return Packet{}, addr, err
+	}
+	p, err := c.decode(buf[:n], addr)
 	if err != nil {
-		// Check if the error is due to the connection being closed.
-		if errors.Is(err, net.ErrClosed) {
-			return nil, nil, fmt.Errorf("connection closed: %w", err) // Return specific error?
+		return Packet{}, addr, err
+	}
+	return p, addr, nil
+}
+
+// decode parses a datagram received from addr into a Packet whose Data aliases data. In
+// strict mode, the packet is also validated. Accepted packets are reported to the observer.
+func (c *Conn) decode(data []byte, addr netip.AddrPort) (Packet, error) {
+	var p Packet
+	if err := p.Header.UnmarshalBinary(data); err != nil {
+		return Packet{}, fmt.Errorf("failed to unmarshal received data as VBAN packet: %w", err)
+	}
+	p.Data = data[HeaderSize:]
+	if c.strict.Load() {
+		if err := p.Validate(); err != nil {
+			return Packet{}, fmt.Errorf("received invalid VBAN packet: %w", err)
 		}
-		// Other potential errors (network issues, etc.)
-		return nil, nil, fmt.Errorf("UDP read error: %w", err)
 	}
+	if o := c.observer.Load(); o != nil {
+		(*o).ObservePacket(p.Header, len(data), addr)
+	}
+	return p, nil
+}
+
+// PacketObserver is notified of the packets received on a Conn (see SetObserver).
+type PacketObserver interface {
+	// ObservePacket is called from the receiving goroutine for every valid packet, with its
+	// header, its size including the header, and the sender's address. It must not block.
+	ObservePacket(h Header, size int, addr netip.AddrPort)
+}
+
+// SetObserver sets an observer that is notified of every valid packet received through
+// Receive, ReceiveInto, ReadPacket and ReceiveBatch, e.g. a StatsCollector. Packets that
+// are malformed or rejected in strict mode are not reported. A nil observer removes it.
+func (c *Conn) SetObserver(o PacketObserver) {
+	if o == nil {
+		c.observer.Store(nil)
+		return
+	}
+	c.observer.Store(&o)
+}
+
+// SetStrict enables or disables strict mode. In strict mode, received packets that fail
+// Packet.Validate are rejected like malformed packets: Receive, ReceiveInto and ReadPacket
+// return an error matching ErrInvalid together with the sender's address, and
+// ReceiveBatch drops them. Strict mode is disabled by default.
+func (c *Conn) SetStrict(strict bool) {
+	c.strict.Store(strict)
+}
+
+// Strict reports whether strict mode is enabled.
+func (c *Conn) Strict() bool {
+	return c.strict.Load()
+}
+
+// readError wraps an error returned by a UDP read.
+func readError(err error) error {
+	// Check if the error is due to the connection being closed.
+	if errors.Is(err, net.ErrClosed) {
+		return fmt.Errorf("%w: %w", ErrClosed, err)
+	}
+	// Other potential errors (network issues, etc.)
+	return fmt.Errorf("UDP read error: %w", err)
+}
+
+// read blocks until a datagram is received into buf and checks its size against the VBAN
+// limits. The returned address is valid whenever data was received, even on error.
+func (c *Conn) read(buf []byte) (int, netip.AddrPort, error) {
+	udpConn, err := c.conn()
+	if err != nil {
+		return 0, netip.AddrPort{}, err
+	}
+
+	// ReadFromUDPAddrPort waits for a packet and, unlike ReadFromUDP, does not allocate.
+	n, addr, err := udpConn.ReadFromUDPAddrPort(buf)
+
+	// Handle read errors
+	if err != nil {
+		return 0, netip.AddrPort{}, readError(err)
+	}
+	// Report IPv4 senders on dual-stack sockets as plain IPv4 addresses.
+	addr = netip.AddrPortFrom(addr.Addr().Unmap(), addr.Port())
 
 	// Basic validation of received data length
 	if n == 0 {
 		// Theoretically possible to receive empty UDP datagrams, though unlikely for VBAN
-		return nil, remoteAddr, errors.New("received empty UDP packet")
+		return 0, addr, fmt.Errorf("%w: received empty UDP packet", ErrShortPacket)
 	}
 	if n > MaxVBANPacketSize {
 		// Packet larger than our buffer + overflow byte could handle, or larger than protocol max.
 		// This indicates an issue, possibly fragmentation or non-VBAN traffic.
-		return nil, remoteAddr, fmt.Errorf("received oversized packet: %d bytes (max allowed %d)", n, MaxVBANPacketSize)
+		return 0, addr, fmt.Errorf("%w: received %d bytes (max allowed %d)", ErrOversized, n, MaxVBANPacketSize)
+	}
+	if n < HeaderSize {
+		return 0, addr, fmt.Errorf("%w: insufficient data for VBAN packet: got %d bytes, need at least %d", ErrShortPacket, n, HeaderSize)
 	}
+	return n, addr, nil
+}
 
-	// Attempt to unmarshal the received bytes into a VBAN Packet struct
-	// Pass only the slice containing the actual received data ([:n]).
-	packet, err := UnmarshalBinary(c.readBuffer[:n])
+// SetReadDeadline sets the deadline for future Receive calls and any currently-blocked
+// Receive call. A zero value for t means Receive will not time out. After the deadline,
+// Receive fails with an error matching os.ErrDeadlineExceeded.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	udpConn, err := c.conn()
 	if err != nil {
-		// Data was received, but it wasn't a valid VBAN packet (e.g., bad magic number)
-		return nil, remoteAddr, fmt.Errorf("failed to unmarshal received data as VBAN packet: %w", err)
+		return err
 	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	return closedError(udpConn.SetReadDeadline(t))
+}
 
-	// Successfully received and parsed a VBAN packet
-	return packet, remoteAddr, nil
+// SetWriteDeadline sets the deadline for future Send calls. A zero value for t means
+// Send will not time out.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	udpConn, err := c.conn()
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeDeadline = t
+	return closedError(udpConn.SetWriteDeadline(t))
+}
+
+// SetDeadline sets both the read and write deadlines.
+func (c *Conn) SetDeadline(t time.Time) error {
+	if err := c.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.SetWriteDeadline(t)
+}
+
+// ReceiveContext is like Receive but returns ctx.Err() if ctx is canceled or its deadline
+// expires before a packet arrives. A deadline set with SetReadDeadline still applies and is
+// restored on return. ReceiveContext must not be called concurrently with other receives.
+func (c *Conn) ReceiveContext(ctx context.Context) (*Packet, *net.UDPAddr, error) {
+	udpConn, err := c.conn()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	c.mu.Lock()
+	restore, err := bindContext(ctx, udpConn.SetReadDeadline, c.readDeadline)
+	c.mu.Unlock()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to set read deadline: %w", closedError(err))
+	}
+	packet, addr, err := c.Receive()
+	c.mu.Lock()
+	restore(c.readDeadline)
+	c.mu.Unlock()
+	if err != nil && addr == nil {
+		return nil, nil, contextError(ctx, err)
+	}
+	return packet, addr, err
+}
+
+// SendContext is like Send but gives up with ctx.Err() if ctx is canceled or its deadline
+// expires before the packet is written. A deadline set with SetWriteDeadline still applies
+// and is restored on return.
+func (c *Conn) SendContext(ctx context.Context, packet *Packet, addr *net.UDPAddr) error {
+	udpConn, err := c.conn()
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	restore, err := bindContext(ctx, udpConn.SetWriteDeadline, c.writeDeadline)
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to set write deadline: %w", closedError(err))
+	}
+	err = c.Send(packet, addr)
+	c.mu.Lock()
+	restore(c.writeDeadline)
+	c.mu.Unlock()
+	if err != nil {
+		return contextError(ctx, err)
+	}
+	return nil
+}
+
+// bindContext applies the earlier of ctx's deadline and the user deadline through
+// setDeadline, and moves the deadline to the past as soon as ctx is canceled so that a
+// pending operation is unblocked. The returned function stops watching ctx and restores
+// the given user deadline.
+func bindContext(ctx context.Context, setDeadline func(time.Time) error, user time.Time) (func(user time.Time), error) {
+	deadline := user
+	if d, ok := ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+		deadline = d
+	}
+	if err := setDeadline(deadline); err != nil {
+		return nil, err
+	}
+	done := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		defer close(done)
+		setDeadline(time.Unix(1, 0))
+	})
+	return func(user time.Time) {
+		if !stop() {
+			<-done // Wait for the callback so it cannot override the restored deadline
+		}
+		setDeadline(user)
+	}, nil
+}
+
+// contextError replaces an I/O error caused by ctx (canceled, or timed out at the context
+// deadline) with the context's error.
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	var netErr net.Error
+	if d, ok := ctx.Deadline(); ok && errors.As(err, &netErr) && netErr.Timeout() && !time.Now().Before(d) {
+		return context.DeadlineExceeded // The socket deadline fired just before the context's timer
+	}
+	return err
 }
 
 // LocalAddr returns the local network address of the underlying UDP connection.
 func (c *Conn) LocalAddr() net.Addr {
-	if c.udpConn == nil {
+	udpConn, err := c.conn()
+	if err != nil {
 		return nil
 	}
-	return c.udpConn.LocalAddr()
+	return udpConn.LocalAddr()
 }
 
 // RemoteAddr returns the remote network address (only meaningful if Conn was created using Dial).
 func (c *Conn) RemoteAddr() net.Addr {
-	if c.udpConn == nil {
+	udpConn, err := c.conn()
+	if err != nil {
 		return nil
 	}
-	return c.udpConn.RemoteAddr()
+	return udpConn.RemoteAddr()
 }
@@ -0,0 +1,138 @@
+package vban
+
+import (
+	"net"
+	"testing"
+)
+
+// loopbackPair returns a Conn listening on the loopback interface and a UDP socket
+// connected to it, closed when the test ends.
+func loopbackPair(tb testing.TB) (*Conn, *net.UDPConn) {
+	tb.Helper()
+	conn, err := Listen(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		tb.Fatalf("Listen: %v", err)
+	}
+	tb.Cleanup(func() { conn.Close() })
+	sender, err := net.DialUDP("udp4", nil, conn.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		tb.Fatalf("DialUDP: %v", err)
+	}
+	tb.Cleanup(func() { sender.Close() })
+	return conn, sender
+}
+
+// testDatagram returns a marshaled 128-sample stereo 16-bit audio packet.
+func testDatagram(tb testing.TB) []byte {
+	tb.Helper()
+	h := NewHeader(ProtocolAudio, "Stream1")
+	h.SetAudioFormat(3, DataTypeINT16, CodecPCM) // 48 kHz
+	if err := h.SetSamplesPerFrame(128); err != nil {
+		tb.Fatal(err)
+	}
+	if err := h.SetChannels(2); err != nil {
+		tb.Fatal(err)
+	}
+	p, err := NewPacket(h, make([]byte, 128*2*2))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	data, err := p.MarshalBinary()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return data
+}
+
+// receiveInto sends datagram and receives it with ReceiveInto.
+func receiveInto(tb testing.TB, conn *Conn, sender *net.UDPConn, datagram []byte, p *Packet) {
+	if _, err := sender.Write(datagram); err != nil {
+		tb.Fatalf("Write: %v", err)
+	}
+	if _, err := conn.ReceiveInto(p); err != nil {
+		tb.Fatalf("ReceiveInto: %v", err)
+	}
+}
+
+// readPacket sends datagram and receives it with ReadPacket.
+func readPacket(tb testing.TB, conn *Conn, sender *net.UDPConn, datagram, buf []byte) {
+	if _, err := sender.Write(datagram); err != nil {
+		tb.Fatalf("Write: %v", err)
+	}
+	if _, _, err := conn.ReadPacket(buf); err != nil {
+		tb.Fatalf("ReadPacket: %v", err)
+	}
+}
+
+func TestReceiveIntoAllocs(t *testing.T) {
+	conn, sender := loopbackPair(t)
+	datagram := testDatagram(t)
+	p := Packet{Data: make([]byte, 0, MaxPacketDataSize)}
+	if allocs := testing.AllocsPerRun(100, func() { receiveInto(t, conn, sender, datagram, &p) }); allocs != 0 {
+		t.Errorf("ReceiveInto allocates %v times per packet, want 0", allocs)
+	}
+}
+
+func TestReadPacketAllocs(t *testing.T) {
+	conn, sender := loopbackPair(t)
+	datagram := testDatagram(t)
+	buf := make([]byte, MaxVBANPacketSize+1)
+	if allocs := testing.AllocsPerRun(100, func() { readPacket(t, conn, sender, datagram, buf) }); allocs != 0 {
+		t.Errorf("ReadPacket allocates %v times per packet, want 0", allocs)
+	}
+}
+
+func TestReadPacketBufferSize(t *testing.T) {
+	conn, _ := loopbackPair(t)
+	// A buffer without room for an overflow byte cannot detect oversized datagrams.
+	if _, _, err := conn.ReadPacket(make([]byte, MaxVBANPacketSize)); err == nil {
+		t.Error("ReadPacket accepted a buffer of MaxVBANPacketSize bytes")
+	}
+}
+
+func TestHeaderUnmarshalBinaryAllocs(t *testing.T) {
+	datagram := testDatagram(t)
+	var h Header
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := h.UnmarshalBinary(datagram); err != nil {
+			t.Fatalf("UnmarshalBinary: %v", err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Header.UnmarshalBinary allocates %v times, want 0", allocs)
+	}
+}
+
+func BenchmarkReceiveInto(b *testing.B) {
+	conn, sender := loopbackPair(b)
+	datagram := testDatagram(b)
+	p := Packet{Data: make([]byte, 0, MaxPacketDataSize)}
+	b.SetBytes(int64(len(datagram)))
+	b.ReportAllocs()
+	for b.Loop() {
+		receiveInto(b, conn, sender, datagram, &p)
+	}
+}
+
+func BenchmarkReadPacket(b *testing.B) {
+	conn, sender := loopbackPair(b)
+	datagram := testDatagram(b)
+	buf := make([]byte, MaxVBANPacketSize+1)
+	b.SetBytes(int64(len(datagram)))
+	b.ReportAllocs()
+	for b.Loop() {
+		readPacket(b, conn, sender, datagram, buf)
+	}
+}
+
+func BenchmarkHeaderUnmarshalBinary(b *testing.B) {
+	datagram := testDatagram(b)
+	var h Header
+	b.SetBytes(HeaderSize)
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := h.UnmarshalBinary(datagram); err != nil {
+			b.Fatalf("UnmarshalBinary: %v", err)
+		}
+	}
+}
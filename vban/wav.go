@@ -0,0 +1,219 @@
+package vban
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+)
+
+// --- WAV / RF64 Files ---
+//
+// WAV files are written with a 28-byte JUNK chunk reserved ahead of the fmt chunk. When
+// the file grows beyond the 4 GB limit of the RIFF size fields, the header is rewritten
+// as RF64 (EBU Tech 3306): the JUNK chunk becomes the ds64 chunk holding the 64-bit
+// sizes, and the 32-bit size fields are set to 0xFFFFFFFF.
+
+const (
+	wavFormatPCM        = 0x0001
+	wavFormatExtensible = 0xFFFE
+	wavFormatIEEEFloat  = 0x0003
+
+	wavDS64Size    = 28                 // Body size of the ds64 (and reserved JUNK) chunk
+	wavSyncEvery   = 1 << 20            // Bytes written between header updates
+	wavMaxRIFFSize = math.MaxUint32 - 1 // Largest RIFF size value (0xFFFFFFFF marks RF64)
+)
+
+// wavSubFormatTail is the common tail of the KSDATAFORMAT_SUBTYPE GUIDs, following the
+// 32-bit format tag.
+var wavSubFormatTail = []byte{0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xAA, 0x00, 0x38, 0x9B, 0x71}
+
+// wavEncoding returns how samples of a VBAN DataType are stored in a WAV file: the
+// container size in bytes, the number of valid bits and whether they are IEEE floats.
+// The bit-packed 12BIT and 10BIT types are stored MSB-aligned in 16-bit containers.
+func wavEncoding(dt DataType) (container, validBits int, float bool) {
+	switch dt & DataTypeMask {
+	case DataType12BIT, DataType10BIT:
+		return 2, dt.BitsPerSample(), false
+	case DataTypeFLOAT32, DataTypeFLOAT64:
+		return dt.Size(), dt.BitsPerSample(), true
+	default:
+		return dt.Size(), dt.BitsPerSample(), false
+	}
+}
+
+// appendWAVSamples appends n samples of the raw VBAN payload src to dst in the WAV
+// layout of the DataType.
+func appendWAVSamples(dst []byte, dt DataType, src []byte, n int) []byte {
+	switch dt & DataTypeMask {
+	case DataType12BIT, DataType10BIT:
+		bits := dt.BitsPerSample()
+		for i := range n {
+			dst = byteOrder.AppendUint16(dst, uint16(readPacked(src, i, bits)<<(16-bits)))
+		}
+		return dst
+	default:
+		return append(dst, src[:dt.PayloadSize(n)]...)
+	}
+}
+
+// wavChannelMask returns the speaker mask of an extensible WAV file. Only mono and stereo
+// are mapped to speaker positions; other layouts are left unassigned.
+func wavChannelMask(channels int) uint32 {
+	switch channels {
+	case 1:
+		return 0x4 // Front center
+	case 2:
+		return 0x3 // Front left, front right
+	default:
+		return 0
+	}
+}
+
+// wavWriter writes uncompressed audio to a WAV file, switching to RF64 when needed.
+// The header sizes are updated periodically, so an interrupted recording remains
+// readable up to the last update.
+type wavWriter struct {
+	f         io.WriteSeeker
+	bw        *bufio.Writer
+	format    AudioFormat
+	frameSize int   // Bytes per sample frame in the file
+	dataStart int64 // File offset of the sample data
+	size      int64 // Sample data bytes written
+	synced    int64 // Data size at the last header update
+	rf64      bool
+}
+
+// newWAVWriter writes the header for the format to f, which must be positioned at the
+// start of the file.
+func newWAVWriter(f io.WriteSeeker, format AudioFormat) (*wavWriter, error) {
+	if err := format.validate(); err != nil {
+		return nil, err
+	}
+	container, validBits, float := wavEncoding(format.DataType)
+	if container == 0 {
+		return nil, fmt.Errorf("unsupported data type: %d", format.DataType)
+	}
+	w := &wavWriter{
+		f:         f,
+		bw:        bufio.NewWriterSize(f, 64<<10),
+		format:    format,
+		frameSize: container * format.Channels,
+	}
+	rate := format.SampleRate()
+	extensible := format.Channels > 2 || validBits > 16 || float || validBits != 8*container
+
+	b := make([]byte, 0, 96)
+	b = append(b, "RIFF\x00\x00\x00\x00WAVE"...)
+	b = append(b, "JUNK"...)
+	b = byteOrder.AppendUint32(b, wavDS64Size)
+	b = append(b, make([]byte, wavDS64Size)...)
+	b = append(b, "fmt "...)
+	if extensible {
+		b = byteOrder.AppendUint32(b, 40)
+		b = byteOrder.AppendUint16(b, wavFormatExtensible)
+	} else {
+		b = byteOrder.AppendUint32(b, 16)
+		b = byteOrder.AppendUint16(b, wavFormatPCM)
+	}
+	b = byteOrder.AppendUint16(b, uint16(format.Channels))
+	b = byteOrder.AppendUint32(b, rate)
+	b = byteOrder.AppendUint32(b, rate*uint32(w.frameSize))
+	b = byteOrder.AppendUint16(b, uint16(w.frameSize))
+	b = byteOrder.AppendUint16(b, uint16(8*container))
+	if extensible {
+		b = byteOrder.AppendUint16(b, 22) // Extension size
+		b = byteOrder.AppendUint16(b, uint16(validBits))
+		b = byteOrder.AppendUint32(b, wavChannelMask(format.Channels))
+		if float {
+			b = byteOrder.AppendUint32(b, wavFormatIEEEFloat)
+		} else {
+			b = byteOrder.AppendUint32(b, wavFormatPCM)
+		}
+		b = append(b, wavSubFormatTail...)
+	}
+	b = append(b, "data\x00\x00\x00\x00"...)
+	w.dataStart = int64(len(b))
+	if _, err := w.bw.Write(b); err != nil {
+		return nil, err
+	}
+	return w, w.sync()
+}
+
+// write appends sample data in the file's layout.
+func (w *wavWriter) write(p []byte) error {
+	n, err := w.bw.Write(p)
+	w.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if w.size-w.synced >= wavSyncEvery {
+		return w.sync()
+	}
+	return nil
+}
+
+// frames returns the number of sample frames written.
+func (w *wavWriter) frames() int64 { return w.size / int64(w.frameSize) }
+
+// sync flushes the buffered data and updates the size fields of the header.
+func (w *wavWriter) sync() error {
+	if err := w.bw.Flush(); err != nil {
+		return err
+	}
+	pad := w.size & 1
+	riffSize := w.dataStart - 8 + w.size + pad
+	if !w.rf64 && riffSize > wavMaxRIFFSize {
+		w.rf64 = true
+	}
+
+	var hdr [48]byte
+	copy(hdr[0:], "RIFF")
+	byteOrder.PutUint32(hdr[4:], uint32(riffSize))
+	copy(hdr[8:], "WAVE")
+	dataSize := uint32(w.size)
+	if w.rf64 {
+		copy(hdr[0:], "RF64")
+		byteOrder.PutUint32(hdr[4:], math.MaxUint32)
+		copy(hdr[12:], "ds64")
+		byteOrder.PutUint32(hdr[16:], wavDS64Size)
+		byteOrder.PutUint64(hdr[20:], uint64(riffSize))
+		byteOrder.PutUint64(hdr[28:], uint64(w.size))
+		byteOrder.PutUint64(hdr[36:], uint64(w.frames()))
+		// hdr[44:48] is the (empty) table length.
+		dataSize = math.MaxUint32
+	} else {
+		copy(hdr[12:], "JUNK")
+		byteOrder.PutUint32(hdr[16:], wavDS64Size)
+	}
+	if _, err := w.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := w.f.Write(hdr[:]); err != nil {
+		return err
+	}
+	var sz [4]byte
+	byteOrder.PutUint32(sz[:], dataSize)
+	if _, err := w.f.Seek(w.dataStart-4, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := w.f.Write(sz[:]); err != nil {
+		return err
+	}
+	if _, err := w.f.Seek(w.dataStart+w.size, io.SeekStart); err != nil {
+		return err
+	}
+	w.synced = w.size
+	return nil
+}
+
+// close writes the pad byte required after odd-sized data and finalizes the header.
+// It does not close the underlying file.
+func (w *wavWriter) close() error {
+	if w.size&1 != 0 {
+		if err := w.bw.WriteByte(0); err != nil {
+			return err
+		}
+	}
+	return w.sync()
+}
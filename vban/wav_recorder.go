@@ -0,0 +1,370 @@
+package vban
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultWAVMaxGap is the longest interruption filled with silence when
+// WAVRecorderConfig.MaxGap is zero.
+const DefaultWAVMaxGap = 10 * time.Second
+
+// wavSyncInterval is the wall-clock interval between header updates of an open file.
+const wavSyncInterval = time.Second
+
+// WAVRecorderConfig configures a WAVRecorder.
+type WAVRecorderConfig struct {
+	StreamName string       // Name of the stream to record (required)
+	Source     *net.UDPAddr // Optional: only accept packets from this IP (and port, if non-zero)
+
+	// Path returns the file name for a recording starting at start. If nil, files are
+	// named "<stream>_<yyyymmdd-hhmmss.mmm>.wav" in the current directory. Existing files
+	// are overwritten.
+	Path func(stream string, start time.Time) string
+
+	MaxDuration time.Duration // Start a new file after this much audio (no limit if zero)
+	MaxSize     int64         // Start a new file before the sample data exceeds this many bytes (no limit if zero)
+	MaxGap      time.Duration // Longest gap filled with silence (DefaultWAVMaxGap if zero)
+
+	// OnClose, if set, is called after a file has been finalized, with the error that
+	// ended it (nil for a normal rotation or Close), or with the error that prevented a
+	// file from being created. It is called after the recorder's lock has been released.
+	OnClose func(path string, err error)
+}
+
+// wavClosed is a finished file waiting to be reported to OnClose.
+type wavClosed struct {
+	path string
+	err  error
+}
+
+// WAVRecorderStats holds the counters of a WAVRecorder.
+type WAVRecorderStats struct {
+	Packets uint64 // Packets written
+	Frames  uint64 // Sample frames written, including inserted silence
+	Silence uint64 // Sample frames of silence inserted for missing packets
+	Dropped uint64 // Late or duplicate packets whose position was already written
+	Files   uint64 // Files started
+}
+
+// WAVRecorder records one incoming VBAN audio stream to WAV files in the stream's native
+// format. INT16 and UINT8 streams with up to two channels are written as plain PCM;
+// INT24, INT32, FLOAT32, FLOAT64, 12BIT, 10BIT (in 16-bit containers) and multichannel
+// streams use WAVE_FORMAT_EXTENSIBLE. Files switch to RF64 when they grow beyond 4 GB,
+// and their headers are updated every second so that an interrupted recording stays
+// playable.
+//
+// Packets are written in NuFrame order as they arrive. Missing packets are replaced by
+// silence so that the file timeline matches the stream; packets arriving after their
+// position was filled are dropped. Gaps longer than MaxGap, a sender restart, a format
+// change or no packets for MaxGap end the current file, and the next packet starts a new
+// one. Files are also rotated by MaxDuration and MaxSize, splitting packets at the exact
+// frame boundary.
+//
+// File errors are reported through OnClose and Err; the next packet starts a new file.
+// WAVRecorder is safe for concurrent use.
+type WAVRecorder struct {
+	conn *Conn
+	cfg  WAVRecorderConfig
+
+	mu     sync.Mutex
+	file   *os.File
+	w      *wavWriter
+	path   string
+	start  time.Time   // Time of the first frame of the current file
+	format AudioFormat // Format of the current file
+	spf    int         // Samples per frame (per channel) of the latest packet
+	next   uint32      // NuFrame of the next expected packet
+	synced time.Time   // Time of the last header update
+	idle   *time.Timer
+	buf    []byte // Conversion and silence buffer
+	stats  WAVRecorderStats
+	err    error
+	closed bool
+
+	done []wavClosed // Files finished while r.mu is held, reported once it is released
+}
+
+// NewWAVRecorder creates a recorder for the stream named in cfg.
+// conn may be nil if packets are delivered through HandlePacket only (e.g. by a Mux).
+func NewWAVRecorder(conn *Conn, cfg WAVRecorderConfig) (*WAVRecorder, error) {
+	if cfg.StreamName == "" {
+		return nil, errors.New("stream name is required")
+	}
+	if cfg.MaxDuration < 0 || cfg.MaxSize < 0 {
+		return nil, errors.New("rotation limits cannot be negative")
+	}
+	if cfg.MaxGap <= 0 {
+		cfg.MaxGap = DefaultWAVMaxGap
+	}
+	if cfg.Path == nil {
+		cfg.Path = defaultWAVPath
+	}
+	return &WAVRecorder{conn: conn, cfg: cfg}, nil
+}
+
+// defaultWAVPath names a file after the stream and its start time, replacing characters
+// that are not allowed in file names.
+func defaultWAVPath(stream string, start time.Time) string {
+	name := strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, stream)
+	return fmt.Sprintf("%s_%s.wav", name, start.Format("20060102-150405.000"))
+}
+
+// Run reads packets from the recorder's Conn until the connection is closed (returning
+// nil) or fails. Malformed packets are skipped. The recorder is closed when Run returns.
+func (r *WAVRecorder) Run() error {
+	if r.conn == nil {
+		return errors.New("recorder has no connection")
+	}
+	defer r.Close()
+	return serve(r.conn, r)
+}
+
+// HandlePacket writes a received packet to the current file. Packets that do not belong
+// to the configured stream are ignored.
+func (r *WAVRecorder) HandlePacket(p *Packet, addr *net.UDPAddr) {
+	if p == nil || !p.Header.SubProtocol().IsAudio() || p.Header.CodecType() != CodecPCM {
+		return
+	}
+	if p.Header.GetStreamName() != r.cfg.StreamName || !matchSource(r.cfg.Source, addr) {
+		return
+	}
+	payload, err := p.audioPayload()
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.unlock()
+	if r.closed {
+		return
+	}
+	now := time.Now()
+	format := p.Header.AudioFormat()
+	spf := int(p.Header.FormatNbs) + 1
+	nu := p.Header.NuFrame
+	if r.w != nil {
+		maxGap := int64(r.cfg.MaxGap.Seconds() * float64(format.SampleRate()) / float64(spf))
+		switch d := int64(int32(nu - r.next)); {
+		case format != r.format || d > maxGap || d < -maxGap:
+			// Format change, long interruption or sender restart.
+			r.closeFile(nil)
+		case d < 0:
+			r.stats.Dropped++
+			return
+		case d > 0:
+			if err := r.writeSilence(int(d) * r.spf); err != nil {
+				r.closeFile(err)
+				return
+			}
+		}
+	}
+	if r.w == nil {
+		if err := r.openFile(format, now); err != nil {
+			return
+		}
+	}
+	r.spf = spf
+	r.next = nu + 1
+
+	r.buf = appendWAVSamples(r.buf[:0], format.DataType, payload, spf*format.Channels)
+	if err := r.writeFrames(r.buf); err != nil {
+		r.closeFile(err)
+		return
+	}
+	r.stats.Packets++
+	if now.Sub(r.synced) >= wavSyncInterval {
+		r.synced = now
+		if err := r.w.sync(); err != nil {
+			r.closeFile(err)
+			return
+		}
+	}
+	if r.idle == nil {
+		r.idle = time.AfterFunc(r.cfg.MaxGap, r.timeout)
+	} else {
+		r.idle.Reset(r.cfg.MaxGap)
+	}
+}
+
+// timeout ends the current file once the stream has been idle for MaxGap.
+func (r *WAVRecorder) timeout() {
+	r.mu.Lock()
+	defer r.unlock()
+	r.closeFile(nil)
+}
+
+// openFile starts a new file for the format. It must be called with r.mu held.
+func (r *WAVRecorder) openFile(format AudioFormat, start time.Time) error {
+	path := r.cfg.Path(r.cfg.StreamName, start)
+	f, err := os.Create(path)
+	if err != nil {
+		r.err = fmt.Errorf("failed to create WAV file: %w", err)
+		r.finished(path, r.err)
+		return r.err
+	}
+	w, err := newWAVWriter(f, format)
+	if err != nil {
+		f.Close()
+		r.err = fmt.Errorf("failed to write WAV header: %w", err)
+		r.finished(path, r.err)
+		return r.err
+	}
+	r.file, r.w, r.path = f, w, path
+	r.start, r.format = start, format
+	r.synced = start
+	r.stats.Files++
+	return nil
+}
+
+// closeFile finalizes the current file, if any. cause is the error that ended it. It
+// returns the error reported for the file. It must be called with r.mu held.
+func (r *WAVRecorder) closeFile(cause error) error {
+	if r.w == nil {
+		return nil
+	}
+	err := cause
+	if err == nil {
+		err = r.w.close()
+	}
+	if cerr := r.file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		if cause == nil {
+			err = fmt.Errorf("failed to finalize WAV file: %w", err)
+		} else {
+			err = fmt.Errorf("failed to write WAV file: %w", err)
+		}
+		r.err = err
+	}
+	r.finished(r.path, err)
+	r.file, r.w, r.path = nil, nil, ""
+	return err
+}
+
+// finished queues a file for OnClose. It must be called with r.mu held.
+func (r *WAVRecorder) finished(path string, err error) {
+	if r.cfg.OnClose != nil {
+		r.done = append(r.done, wavClosed{path: path, err: err})
+	}
+}
+
+// unlock releases r.mu and then reports the files finished while it was held to OnClose.
+func (r *WAVRecorder) unlock() {
+	done := r.done
+	r.done = nil
+	r.mu.Unlock()
+	for _, c := range done {
+		r.cfg.OnClose(c.path, c.err)
+	}
+}
+
+// limit returns the number of frames that may still be written to the current file. It
+// must be called with r.mu held.
+func (r *WAVRecorder) limit() int64 {
+	n := int64(-1)
+	if r.cfg.MaxDuration > 0 {
+		n = max(1, int64(r.cfg.MaxDuration.Seconds()*float64(r.format.SampleRate()))) - r.w.frames()
+	}
+	if r.cfg.MaxSize > 0 {
+		s := max(1, r.cfg.MaxSize/int64(r.w.frameSize)) - r.w.frames()
+		if n < 0 || s < n {
+			n = s
+		}
+	}
+	return n
+}
+
+// writeFrames writes whole frames in the file layout, rotating files at the configured
+// limits. It must be called with r.mu held.
+func (r *WAVRecorder) writeFrames(data []byte) error {
+	frameSize := r.w.frameSize
+	for len(data) > 0 {
+		room := r.limit()
+		if room == 0 {
+			// Continue the timeline seamlessly in the next file.
+			start := r.start.Add(time.Duration(float64(r.w.frames()) / float64(r.format.SampleRate()) * float64(time.Second)))
+			format := r.format
+			r.closeFile(nil)
+			if err := r.openFile(format, start); err != nil {
+				return err
+			}
+			continue
+		}
+		n := len(data)
+		if room > 0 {
+			n = int(min(int64(n), room*int64(frameSize)))
+		}
+		if err := r.w.write(data[:n]); err != nil {
+			return err
+		}
+		r.stats.Frames += uint64(n / frameSize)
+		data = data[n:]
+	}
+	return nil
+}
+
+// writeSilence writes the given number of sample frames of silence. It must be called
+// with r.mu held.
+func (r *WAVRecorder) writeSilence(frames int) error {
+	fill := byte(0)
+	if r.format.DataType == DataTypeUINT8 {
+		fill = 0x80 // Unsigned 8-bit silence is the mid value
+	}
+	frameSize := r.w.frameSize
+	for frames > 0 {
+		n := min(frames, 4096)
+		r.buf = append(r.buf[:0], make([]byte, n*frameSize)...)
+		if fill != 0 {
+			for i := range r.buf {
+				r.buf[i] = fill
+			}
+		}
+		if err := r.writeFrames(r.buf); err != nil {
+			return err
+		}
+		frames -= n
+		r.stats.Silence += uint64(n)
+	}
+	return nil
+}
+
+// Stats returns a snapshot of the recorder's counters.
+func (r *WAVRecorder) Stats() WAVRecorderStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+// Err returns the most recent file error, or nil.
+func (r *WAVRecorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Close finalizes the current file and stops recording. It returns the error of
+// finalizing the file, if any.
+func (r *WAVRecorder) Close() error {
+	r.mu.Lock()
+	defer r.unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	if r.idle != nil {
+		r.idle.Stop()
+	}
+	return r.closeFile(nil)
+}